@@ -15,17 +15,59 @@
 package main
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"strings"
 	"talend/vault-sidecar-injector/pkg/certs"
 	"talend/vault-sidecar-injector/pkg/k8s"
+	"time"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
 )
 
 func genCertificates() error {
+	caLifetime := certParameters.CALifetime
+	if caLifetime == 0 {
+		caLifetime = time.Duration(certParameters.CertLifetime) * 365 * 24 * time.Hour
+	}
+
+	// Kubernetes client not needed if certificates are written to files (unless provided CA is read from a secret)
+	var k8sctl *k8s.K8SClient
+	if certParameters.OutputDir == "" || certParameters.SigningCASecretName != "" {
+		var err error
+		if k8sctl, err = k8s.New(
+			&k8sClientConfig,
+			&k8s.WebhookData{
+				WebhookSecretName: certParameters.CertSecretName,
+				WebhookCACertName: certParameters.CACertFile,
+				WebhookCertName:   certParameters.CertFile,
+				WebhookKeyName:    certParameters.KeyFile,
+				WebhookCAKeyName:  certParameters.CAKeyFile,
+			}); err != nil {
+			return err
+		}
+	}
+
+	// Provided CA to issue webhook certificate from (optional)
+	signingCACert, signingCAKey, err := loadSigningCA(k8sctl)
+	if err != nil {
+		return err
+	}
+
 	// Generate certificates and key
 	cert := &certs.Cert{
-		CN:       "Vault Sidecar Injector",
-		Hosts:    strings.Split(certParameters.CertHostnames, ","),
-		Lifetime: certParameters.CertLifetime,
+		CN:                "Vault Sidecar Injector",
+		Hosts:             strings.Split(certParameters.CertHostnames, ","),
+		CALifetime:        caLifetime,
+		LeafLifetime:      certParameters.LeafLifetime,
+		KeyAlgorithm:      certParameters.KeyAlgorithm,
+		SigningCACert:     signingCACert,
+		SigningCAKey:      signingCAKey,
+		StoreSigningCAKey: certParameters.StoreSigningCAKey,
 	}
 
 	bundle, err := cert.GenerateWebhookBundle()
@@ -33,20 +75,158 @@ func genCertificates() error {
 		return err
 	}
 
-	// Create Kubernetes Secret
-	return k8s.New(
+	if certParameters.OutputDir != "" {
+		// Write PEM files (e.g. to seal them before committing to Git). CA private key only written if explicitly requested.
+		files := &certs.BundleFiles{
+			CACert:  certParameters.CACertFile,
+			Cert:    certParameters.CertFile,
+			PrivKey: certParameters.KeyFile,
+		}
+
+		if certParameters.OutputCAKey {
+			files.CAPrivKey = certParameters.CAKeyFile
+		}
+
+		err = bundle.WriteFiles(certParameters.OutputDir, files)
+	} else {
+		// Create Kubernetes Secret
+		err = k8sctl.CreateCertSecret(bundle.CACert, bundle.Cert, bundle.PrivKey, bundle.CAPrivKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Value to set as 'caBundle' in webhook configurations
+	if certParameters.PrintCABundle {
+		fmt.Println(base64.StdEncoding.EncodeToString(bundle.CACert))
+	}
+
+	return nil
+}
+
+// Return provided CA certificate and private key (PEM-encoded), either from files or from Kubernetes secret. Nothing if no CA is provided.
+func loadSigningCA(k8sctl *k8s.K8SClient) ([]byte, []byte, error) {
+	if certParameters.SigningCASecretName != "" {
+		secret, err := k8sctl.GetSecret(certParameters.SigningCASecretName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], nil
+	}
+
+	if certParameters.SigningCACertFile == "" && certParameters.SigningCAKeyFile == "" {
+		return nil, nil, nil
+	}
+
+	if certParameters.SigningCACertFile == "" || certParameters.SigningCAKeyFile == "" {
+		klog.Error("Both CA certificate and private key files have to be provided")
+		return nil, nil, errors.New("Missing CA certificate or private key file")
+	}
+
+	caCert, err := ioutil.ReadFile(certParameters.SigningCACertFile)
+	if err != nil {
+		klog.Errorf("Failed to read CA certificate file: %v", err)
+		return nil, nil, err
+	}
+
+	caKey, err := ioutil.ReadFile(certParameters.SigningCAKeyFile)
+	if err != nil {
+		klog.Errorf("Failed to read CA private key file: %v", err)
+		return nil, nil, err
+	}
+
+	return caCert, caKey, nil
+}
+
+func rotateCertificates() error {
+	// Host names taken from current webhook certificate if not provided
+	var hosts []string
+	if certParameters.CertHostnames != "" {
+		hosts = strings.Split(certParameters.CertHostnames, ",")
+	}
+
+	cert := &certs.Cert{
+		Hosts:        hosts,
+		CALifetime:   certParameters.CALifetime,
+		LeafLifetime: certParameters.LeafLifetime,
+		KeyAlgorithm: certParameters.KeyAlgorithm,
+	}
+
+	k8sctl, err := k8s.New(
+		&k8sClientConfig,
 		&k8s.WebhookData{
 			WebhookSecretName: certParameters.CertSecretName,
 			WebhookCACertName: certParameters.CACertFile,
 			WebhookCertName:   certParameters.CertFile,
 			WebhookKeyName:    certParameters.KeyFile,
-		}).CreateCertSecret(bundle.CACert, bundle.Cert, bundle.PrivKey)
+			WebhookCAKeyName:  certParameters.CAKeyFile,
+			WebhookCfgName:    certParameters.WebhookCfgName,
+		})
+	if err != nil {
+		return err
+	}
+
+	// Update Kubernetes Secret (and webhook configurations' 'caBundle' if name provided)
+	_, err = k8sctl.RotateCertSecret(cert, certs.RotationOptions{
+		Force:    true,
+		RotateCA: certParameters.RotateCA,
+		Overlap:  certParameters.CAOverlap,
+	})
+
+	return err
 }
 
 func deleteCertificates() error {
+	k8sctl, err := k8s.New(
+		&k8sClientConfig,
+		&k8s.WebhookData{
+			WebhookSecretName: certParameters.CertSecretName,
+		})
+	if err != nil {
+		return err
+	}
+
 	// Delete Kubernetes Secret
-	return k8s.New(
+	return k8sctl.DeleteCertSecret()
+}
+
+func inspectCertificates() error {
+	k8sctl, err := k8s.New(
+		&k8sClientConfig,
 		&k8s.WebhookData{
 			WebhookSecretName: certParameters.CertSecretName,
-		}).DeleteCertSecret()
+		})
+	if err != nil {
+		return err
+	}
+
+	secret, err := k8sctl.GetCertSecret()
+	if err != nil {
+		return err
+	}
+
+	// Output is a YAML stream: CA certificates first (several during CA rollover), then webhook certificate
+	for idx, entry := range []struct{ title, name string }{
+		{"CA certificates", certParameters.CACertFile},
+		{"Webhook certificate", certParameters.CertFile},
+	} {
+		infos, err := certs.Inspect(secret.Data[entry.name])
+		if err != nil {
+			klog.Errorf("Failed to inspect '%s' entry of secret %s: %v", entry.name, secret.Name, err)
+			return err
+		}
+
+		out, err := yaml.Marshal(infos)
+		if err != nil {
+			return err
+		}
+
+		if idx > 0 {
+			fmt.Println("---")
+		}
+		fmt.Printf("# %s (%s/%s: %s)\n%s", entry.title, secret.Namespace, secret.Name, entry.name, string(out))
+	}
+
+	return nil
 }
@@ -16,13 +16,18 @@ package main
 
 // Operational subcommands
 const (
-	CertCmd    = "cert"
-	WebhookCmd = "webhook"
-	VersionCmd = "version"
+	CertCmd       = "cert"
+	WebhookCmd    = "webhook"
+	RenderCmd     = "render"
+	RegisterCmd   = "register"
+	UnregisterCmd = "unregister"
+	VersionCmd    = "version"
 )
 
 // Certificate operations
 const (
-	CreateCert = "create"
-	DeleteCert = "delete"
+	CreateCert  = "create"
+	DeleteCert  = "delete"
+	RotateCert  = "rotate"
+	InspectCert = "inspect"
 )
@@ -18,6 +18,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"talend/vault-sidecar-injector/pkg/certs"
+	"time"
 
 	"k8s.io/klog"
 )
@@ -25,13 +27,28 @@ import (
 func parseFlags() string {
 	// Cert command parameters
 	certCmd := flag.NewFlagSet(CertCmd, flag.ExitOnError)
-	certCmd.StringVar(&certParameters.CertOperation, "certop", CreateCert, "operation on webhook certificates (create, delete)")
+	certCmd.StringVar(&certParameters.CertOperation, "certop", CreateCert, "operation on webhook certificates (create, delete, rotate, inspect)")
 	certCmd.StringVar(&certParameters.CertSecretName, "certsecretname", "", "name of generated or provided Kubernetes secret storing webhook certificates and private key")
 	certCmd.StringVar(&certParameters.CertHostnames, "certhostnames", "", "host names to register in webhook certificate (comma-separated list)")
-	certCmd.IntVar(&certParameters.CertLifetime, "certlifetime", 10, "lifetime in years for generated certificates")
+	certCmd.IntVar(&certParameters.CertLifetime, "certlifetime", 10, "lifetime in years for generated certificates (ignored if califetime is set)")
+	certCmd.Var((*lifetimeValue)(&certParameters.CALifetime), "califetime", "lifetime of generated CA certificate, in days (e.g. '3650d') or hours (e.g. '87600h'). On creation, default to certlifetime. On rotation, default to current CA's lifetime")
+	certCmd.Var((*lifetimeValue)(&certParameters.LeafLifetime), "leaflifetime", "lifetime of generated webhook certificate, in days (e.g. '30d') or hours (e.g. '720h'). On creation, default to CA's lifetime. On rotation, default to current webhook certificate's lifetime")
+	certCmd.StringVar(&certParameters.KeyAlgorithm, "keyalgorithm", "", "algorithm of generated private keys: ecdsa-p256, ecdsa-p384, rsa-2048, rsa-3072, rsa-4096 or ed25519. On creation, default to ecdsa-p256. On rotation, default to algorithm of current keys")
 	certCmd.StringVar(&certParameters.CACertFile, "cacertfile", "ca.crt", "default filename for webhook CA certificate (PEM-encoded) in generated or provided k8s secret")
 	certCmd.StringVar(&certParameters.CertFile, "certfile", "tls.crt", "default filename for webhook certificate (PEM-encoded) in generated or provided k8s secret")
 	certCmd.StringVar(&certParameters.KeyFile, "keyfile", "tls.key", "default filename for webhook private key (PEM-encoded) in generated or provided k8s secret")
+	certCmd.StringVar(&certParameters.CAKeyFile, "cakeyfile", "ca.key", "default filename for webhook CA private key (PEM-encoded) in generated k8s secret, used to issue new webhook certificates on rotation")
+	certCmd.StringVar(&certParameters.SigningCACertFile, "signingcacertfile", "", "file containing CA certificate (PEM-encoded) to issue webhook certificate from, instead of generating a self-signed CA (e.g. corporate intermediate CA)")
+	certCmd.StringVar(&certParameters.SigningCAKeyFile, "signingcakeyfile", "", "file containing private key (PEM-encoded) of the CA provided with signingcacertfile")
+	certCmd.StringVar(&certParameters.SigningCASecretName, "signingcasecret", "", "name of Kubernetes secret, in webhook's namespace, storing CA certificate ('tls.crt' entry) and private key ('tls.key' entry) to issue webhook certificate from, instead of generating a self-signed CA")
+	certCmd.BoolVar(&certParameters.StoreSigningCAKey, "storesigningcakey", false, "store private key of provided CA in generated k8s secret (cakeyfile entry) so that webhook certificate can be rotated. Anyone able to read the secret then gets the CA private key")
+	certCmd.BoolVar(&certParameters.RotateCA, "rotateca", false, "on rotation, roll the CA (new CA issuing new webhook certificate) instead of only issuing new webhook certificate")
+	certCmd.DurationVar(&certParameters.CAOverlap, "caoverlap", 24*time.Hour, "on rotation, period during which previous CA is kept in CA bundle (so that webhook certificate it issued is still trusted)")
+	certCmd.StringVar(&certParameters.OutputDir, "outputdir", "", "on creation, directory to write CA certificate, webhook certificate and private key to (PEM files named after cacertfile, certfile and keyfile) instead of creating Kubernetes secret")
+	certCmd.BoolVar(&certParameters.OutputCAKey, "outputcakey", false, "with outputdir, also write CA private key (PEM file named after cakeyfile), needed by the webhook to rotate certificates")
+	certCmd.BoolVar(&certParameters.PrintCABundle, "printcabundle", false, "on creation, print base64-encoded CA certificate to set as 'caBundle' of webhook configurations")
+	addK8SClientFlags(certCmd)
+	certCmd.StringVar(&certParameters.WebhookCfgName, "webhookcfgname", "", "on rotation, name of MutatingWebhookConfiguration resource (and of optional ValidatingWebhookConfiguration resource) to update with new CA bundle right away")
 
 	// Webhook command parameters
 	webhookCmd := flag.NewFlagSet(WebhookCmd, flag.ExitOnError)
@@ -40,15 +57,53 @@ func parseFlags() string {
 	webhookCmd.StringVar(&webhookParameters.CACertFile, "cacertfile", "ca.crt", "PEM-encoded webhook CA certificate")
 	webhookCmd.StringVar(&webhookParameters.CertFile, "certfile", "tls.crt", "PEM-encoded webhook certificate used for TLS")
 	webhookCmd.StringVar(&webhookParameters.KeyFile, "keyfile", "tls.key", "PEM-encoded webhook private key used for TLS")
-	webhookCmd.StringVar(&webhookParameters.WebhookCfgName, "webhookcfgname", "", "name of MutatingWebhookConfiguration resource")
-	webhookCmd.StringVar(&webhookParameters.AnnotationKeyPrefix, "annotationkeyprefix", "sidecar.vault", "annotations key prefix")
-	webhookCmd.StringVar(&webhookParameters.AppLabelKey, "applabelkey", "application.name", "key for application label")
-	webhookCmd.StringVar(&webhookParameters.AppServiceLabelKey, "appservicelabelkey", "service.name", "key for application's service label")
-	webhookCmd.StringVar(&webhookParameters.InjectionCfgFile, "injectioncfgfile", "", "file containing the mutation configuration (initcontainers, sidecars, volumes, ...)")
-	webhookCmd.StringVar(&webhookParameters.ProxyCfgFile, "proxycfgfile", "", "file containing Vault proxy configuration")
-	webhookCmd.StringVar(&webhookParameters.TemplateBlockFile, "tmplblockfile", "", "file containing the template block")
-	webhookCmd.StringVar(&webhookParameters.TemplateDefaultFile, "tmpldefaultfile", "", "file containing the default template")
-	webhookCmd.StringVar(&webhookParameters.PodLifecycleHooksFile, "podlchooksfile", "", "file containing the lifecycle hooks to inject in the requesting pod")
+	webhookCmd.DurationVar(&webhookParameters.CertExpiryThreshold, "certexpirythreshold", 24*time.Hour, "readiness fails if webhook certificate expires within this duration")
+	webhookCmd.DurationVar(&webhookParameters.CertWatchInterval, "certwatchinterval", 10*time.Second, "polling interval to detect and reload changes in webhook certificate files (0 to disable)")
+	webhookCmd.StringVar(&webhookParameters.CertSecretName, "certsecretname", "", "name of Kubernetes secret storing webhook certificates and private key (required for certificate rotation, caBundle is then reconciled from this secret)")
+	webhookCmd.StringVar(&webhookParameters.CAKeyFile, "cakeyfile", "ca.key", "filename for webhook CA private key (PEM-encoded) in Kubernetes secret, used to issue new webhook certificates on rotation")
+	webhookCmd.Var((*lifetimeValue)(&webhookParameters.CALifetime), "califetime", "lifetime of CA certificate issued on rotation, in days (e.g. '3650d') or hours (e.g. '87600h'). Default to current CA's lifetime")
+	webhookCmd.Var((*lifetimeValue)(&webhookParameters.LeafLifetime), "leaflifetime", "lifetime of webhook certificate issued on rotation, in days (e.g. '30d') or hours (e.g. '720h'). Default to current webhook certificate's lifetime")
+	webhookCmd.StringVar(&webhookParameters.KeyAlgorithm, "keyalgorithm", "", "algorithm of private keys generated on rotation: ecdsa-p256, ecdsa-p384, rsa-2048, rsa-3072, rsa-4096 or ed25519. Default to algorithm of current keys")
+	webhookCmd.BoolVar(&webhookParameters.CertRotation, "certrotation", false, "rotate webhook certificates stored in Kubernetes secret in background (only the replica holding the Lease does it)")
+	webhookCmd.Float64Var(&webhookParameters.CertRotationFraction, "certrotationfraction", 0.67, "rotate webhook certificate (or CA) once this fraction of its lifetime has elapsed")
+	webhookCmd.DurationVar(&webhookParameters.CertRotationOverlap, "certrotationoverlap", 24*time.Hour, "period during which previous CA is kept in CA bundle after a CA rollover")
+	webhookCmd.DurationVar(&webhookParameters.CertRotationInterval, "certrotationinterval", time.Hour, "period to check whether webhook certificates have to be rotated")
+	webhookCmd.DurationVar(&webhookParameters.ReadTimeout, "readtimeout", 10*time.Second, "max duration for reading the entire request, including the body (0 for no timeout)")
+	webhookCmd.DurationVar(&webhookParameters.WriteTimeout, "writetimeout", 30*time.Second, "max duration before timing out writes of the response (0 for no timeout)")
+	webhookCmd.DurationVar(&webhookParameters.IdleTimeout, "idletimeout", 120*time.Second, "max amount of time to wait for the next request on keep-alive connections (0 for no timeout)")
+	webhookCmd.Int64Var(&webhookParameters.MaxRequestBodySize, "maxrequestbodysize", 10*1024*1024, "max size in bytes of admission requests' body, larger requests are rejected with HTTP 413 (0 for no limit)")
+	webhookCmd.IntVar(&webhookParameters.MaxConcurrentRequests, "maxconcurrentrequests", 100, "max number of admission requests processed at the same time, other requests are rejected with HTTP 429 (0 for no limit)")
+	webhookCmd.DurationVar(&webhookParameters.ShutdownDelay, "shutdowndelay", 5*time.Second, "on shutdown, time to wait once replica is marked unready (so that endpoints get updated) before draining webhook server")
+	webhookCmd.DurationVar(&webhookParameters.ShutdownTimeout, "shutdowntimeout", 20*time.Second, "on shutdown, max duration to drain in-flight admission requests (0 for no timeout)")
+	webhookCmd.StringVar(&webhookParameters.AuditLog, "auditlog", "", "file to write JSON audit records of admission decisions to ('-' for stdout, disabled if empty)")
+	webhookCmd.StringVar(&webhookParameters.OTLPEndpoint, "otlpendpoint", "", "OTLP/HTTP endpoint URL to export traces to (if empty, tracing is enabled only if OTEL_EXPORTER_OTLP_ENDPOINT env var is set)")
+	webhookCmd.StringVar(&webhookParameters.WebhookCfgName, "webhookcfgname", "", "name of MutatingWebhookConfiguration resource (and of optional ValidatingWebhookConfiguration resource)")
+	webhookCmd.BoolVar(&webhookParameters.CABundleReconcile, "cabundlereconcile", false, "continuously reconcile 'caBundle' of webhook configurations with CA certificate (only the replica holding the Lease does it)")
+	webhookCmd.StringVar(&webhookParameters.LeaseNamespace, "leasenamespace", "", "namespace of the Leases used for leader election (default to webhook's namespace)")
+	webhookCmd.BoolVar(&webhookParameters.Dev, "dev", false, "local development mode: serve webhook on localhost with certificates generated in memory, without any Kubernetes cluster (no registration, caBundle patching, certificate rotation or namespace defaults)")
+	webhookCmd.BoolVar(&webhookParameters.Register, "register", false, "on startup, create or update MutatingWebhookConfiguration named webhookcfgname (see registration flags)")
+	addRegistrationFlags(webhookCmd)
+	addK8SClientFlags(webhookCmd)
+	webhookCmd.DurationVar(&webhookParameters.ConfigWatchInterval, "cfgwatchinterval", 10*time.Second, "polling interval to detect and reload changes in configuration files (0 to disable)")
+	addConfigFlags(webhookCmd)
+
+	// Render command parameters
+	renderCmd := flag.NewFlagSet(RenderCmd, flag.ExitOnError)
+	renderCmd.StringVar(&renderParameters.ManifestFile, "manifest", "-", "file containing the Deployment, Job or Pod manifest to render ('-' to read from stdin)")
+	addConfigFlags(renderCmd)
+
+	// Register command parameters
+	registerCmd := flag.NewFlagSet(RegisterCmd, flag.ExitOnError)
+	registerCmd.StringVar(&registerParameters.WebhookCfgName, "webhookcfgname", "", "name of MutatingWebhookConfiguration resource to create or update")
+	registerCmd.StringVar(&registerParameters.CertSecretName, "certsecretname", "", "name of Kubernetes secret storing webhook CA certificate (if empty, CA certificate is read from cacertfile)")
+	registerCmd.StringVar(&registerParameters.CACertFile, "cacertfile", "ca.crt", "PEM-encoded webhook CA certificate (or name of secret entry if certsecretname is set)")
+	addRegistrationFlags(registerCmd)
+	addK8SClientFlags(registerCmd)
+
+	// Unregister command parameters
+	unregisterCmd := flag.NewFlagSet(UnregisterCmd, flag.ExitOnError)
+	unregisterCmd.StringVar(&registerParameters.WebhookCfgName, "webhookcfgname", "", "name of MutatingWebhookConfiguration resource to delete")
+	addK8SClientFlags(unregisterCmd)
 
 	if len(os.Args) == 1 {
 		usage(os.Args[0])
@@ -64,6 +119,15 @@ func parseFlags() string {
 	case WebhookCmd:
 		klog.InitFlags(webhookCmd)
 		webhookCmd.Parse(os.Args[2:])
+	case RenderCmd:
+		klog.InitFlags(renderCmd)
+		renderCmd.Parse(os.Args[2:])
+	case RegisterCmd:
+		klog.InitFlags(registerCmd)
+		registerCmd.Parse(os.Args[2:])
+	case UnregisterCmd:
+		klog.InitFlags(unregisterCmd)
+		unregisterCmd.Parse(os.Args[2:])
 	case VersionCmd:
 		fmt.Println("VSI (Vault Sidecar Injector) version " + VERSION)
 		os.Exit(0)
@@ -75,10 +139,68 @@ func parseFlags() string {
 	return vsiSubcommand
 }
 
+// Flags shared by commands loading Vault Sidecar Injector's config
+func addConfigFlags(cmd *flag.FlagSet) {
+	cmd.StringVar(&webhookParameters.AnnotationKeyPrefix, "annotationkeyprefix", "sidecar.vault", "annotations key prefix")
+	cmd.StringVar(&webhookParameters.AppLabelKey, "applabelkey", "application.name", "key for application label")
+	cmd.StringVar(&webhookParameters.AppServiceLabelKey, "appservicelabelkey", "service.name", "key for application's service label")
+	cmd.StringVar(&webhookParameters.SkipNamespaces, "skipnamespaces", "", "comma-separated list of namespaces (or glob patterns, e.g. 'monitoring-*') where injection is never performed, in addition to kube-system and kube-public")
+	cmd.StringVar(&webhookParameters.InjectionCfgFile, "injectioncfgfile", "", "file containing the mutation configuration (initcontainers, sidecars, volumes, ...)")
+	cmd.StringVar(&webhookParameters.ProxyCfgFile, "proxycfgfile", "", "file containing Vault proxy configuration")
+	cmd.StringVar(&webhookParameters.TemplateBlockFile, "tmplblockfile", "", "file containing the template block")
+	cmd.StringVar(&webhookParameters.TemplateDefaultFile, "tmpldefaultfile", "", "file containing the default template")
+	cmd.StringVar(&webhookParameters.PodLifecycleHooksFile, "podlchooksfile", "", "file containing the lifecycle hooks to inject in the requesting pod")
+	cmd.StringVar(&webhookParameters.PolicyFile, "policyfile", "", "file containing the admission policy (CEL rules injection requests must comply with). No policy if empty")
+}
+
+// Flags selecting the Kubernetes cluster to connect to
+func addK8SClientFlags(cmd *flag.FlagSet) {
+	cmd.StringVar(&k8sClientConfig.Kubeconfig, "kubeconfig", "", "path to kubeconfig file, to run outside of Kubernetes (if empty: in-cluster config, then KUBECONFIG env var or ~/.kube/config)")
+	cmd.StringVar(&k8sClientConfig.Context, "context", "", "kubeconfig context to use (current context if empty)")
+}
+
+// Flags describing the webhook entry of the MutatingWebhookConfiguration to register
+func addRegistrationFlags(cmd *flag.FlagSet) {
+	cmd.StringVar(&registerParameters.ServiceName, "servicename", "", "name of webhook's service referenced in MutatingWebhookConfiguration")
+	cmd.StringVar(&registerParameters.ServiceNamespace, "servicenamespace", "", "namespace of webhook's service (default to current namespace)")
+	cmd.IntVar(&registerParameters.ServicePort, "serviceport", 443, "port of webhook's service")
+	cmd.StringVar(&registerParameters.Path, "registerpath", "/mutate", "URL path of webhook's mutation endpoint")
+	cmd.StringVar(&registerParameters.Operations, "operations", "CREATE", "operations on pods sent to the webhook (comma-separated list of CREATE, UPDATE, DELETE, CONNECT or *)")
+	cmd.StringVar(&registerParameters.NamespaceSelector, "namespaceselector", "", "label selector on namespaces of pods sent to the webhook (e.g. 'vault-injection=enabled'). All namespaces if empty")
+	cmd.StringVar(&registerParameters.ObjectSelector, "objectselector", "", "label selector on pods sent to the webhook. All pods if empty")
+	cmd.StringVar(&registerParameters.FailurePolicy, "failurepolicy", "Ignore", "how errors and timeouts of the webhook are handled (Ignore or Fail)")
+	cmd.DurationVar(&registerParameters.Timeout, "registertimeout", 10*time.Second, "timeout of calls to the webhook by the API server (between 1s and 30s)")
+	cmd.StringVar(&registerParameters.ReinvocationPolicy, "reinvocationpolicy", "IfNeeded", "whether webhook is called again if other admission plugins modify the pod (Never or IfNeeded)")
+}
+
+// lifetimeValue is a flag accepting lifetimes in days (e.g. '30d') or as durations (e.g. '720h')
+type lifetimeValue time.Duration
+
+func (l *lifetimeValue) Set(value string) error {
+	lifetime, err := certs.ParseLifetime(value)
+	if err != nil {
+		return err
+	}
+
+	*l = lifetimeValue(lifetime)
+	return nil
+}
+
+func (l *lifetimeValue) String() string {
+	if lifetime := time.Duration(*l); lifetime%(24*time.Hour) == 0 && lifetime != 0 {
+		return fmt.Sprintf("%dd", lifetime/(24*time.Hour))
+	}
+
+	return time.Duration(*l).String()
+}
+
 func usage(program string) {
 	fmt.Printf("Usage: %s <command> [<args>]\n\nCommands:\n", program)
 	fmt.Printf("  %s\n", CertCmd)
 	fmt.Printf("  %s\n", WebhookCmd)
+	fmt.Printf("  %s\n", RenderCmd)
+	fmt.Printf("  %s\n", RegisterCmd)
+	fmt.Printf("  %s\n", UnregisterCmd)
 	fmt.Printf("  %s\n", VersionCmd)
 	fmt.Printf("\nUse \"%s <command> --help\" for more information about a given command.\n", program)
 }
@@ -22,6 +22,9 @@ import (
 	"os/signal"
 	"syscall"
 	"talend/vault-sidecar-injector/pkg/config"
+	"talend/vault-sidecar-injector/pkg/k8s"
+	"talend/vault-sidecar-injector/pkg/tracing"
+	"talend/vault-sidecar-injector/pkg/webhook"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/klog"
@@ -31,8 +34,11 @@ var (
 	// VERSION stores current version. Set in Makefile (see build flag -ldflags "-X=main.VERSION=$(VERSION)")
 	VERSION string
 
-	certParameters    config.CertParameters
-	webhookParameters config.WhSvrParameters
+	certParameters     config.CertParameters
+	webhookParameters  config.WhSvrParameters
+	renderParameters   config.RenderParameters
+	registerParameters config.RegisterParameters
+	k8sClientConfig    k8s.ClientConfig
 )
 
 func main() {
@@ -47,20 +53,58 @@ func main() {
 			if deleteCertificates() != nil {
 				os.Exit(1)
 			}
+		case RotateCert: // Issue new certificate (and CA if requested) from certificates and private keys stored in K8S secret
+			if rotateCertificates() != nil {
+				os.Exit(1)
+			}
+		case InspectCert: // Print details of certificates stored in K8S secret
+			if inspectCertificates() != nil {
+				os.Exit(1)
+			}
 		default:
 			klog.Errorf("Unsupported certificate operation: %s", certParameters.CertOperation)
 			os.Exit(1)
 		}
+	case RenderCmd: // Print JSON Patch and mutated pod for provided manifest
+		if render() != nil {
+			os.Exit(1)
+		}
+	case RegisterCmd: // Create or update MutatingWebhookConfiguration
+		if registerWebhook() != nil {
+			os.Exit(1)
+		}
+	case UnregisterCmd: // Delete MutatingWebhookConfiguration
+		if unregisterWebhook() != nil {
+			os.Exit(1)
+		}
 	case WebhookCmd:
-		// Init and load config
-		vaultInjector, err := createVaultInjector()
+		// Export traces if OTLP endpoint is provided
+		shutdownTracing, err := tracing.Init(webhookParameters.OTLPEndpoint, VERSION)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		// Init and load config, either to serve Kubernetes API server or for local development
+		stopCh := make(chan struct{})
+		var vaultInjector *webhook.VaultInjector
+		if webhookParameters.Dev {
+			vaultInjector, err = createDevVaultInjector()
+		} else {
+			vaultInjector, err = startVaultInjector(stopCh)
+		}
 		if err != nil {
 			os.Exit(1)
 		}
 
+		// Reload config on change
+		watchConfig(vaultInjector, stopCh)
+
 		// Define http server and server handler
 		mux := http.NewServeMux()
 		mux.HandleFunc("/mutate", vaultInjector.Serve)
+		mux.HandleFunc("/validate", vaultInjector.ServeValidate)
+		mux.HandleFunc("/healthz", vaultInjector.Healthz)
+		mux.HandleFunc("/readyz", vaultInjector.Readyz)
 		vaultInjector.Server.Handler = mux
 
 		// Start webhook server in new routine
@@ -75,7 +119,7 @@ func main() {
 		metricsMux.Handle("/metrics", promhttp.Handler())
 
 		metricsServer := &http.Server{
-			Addr:    fmt.Sprintf(":%v", webhookParameters.MetricsPort),
+			Addr:    fmt.Sprintf("%s:%v", metricsHost(), webhookParameters.MetricsPort),
 			Handler: metricsMux,
 		}
 
@@ -92,7 +136,10 @@ func main() {
 		<-signalChan
 
 		klog.Infof("Got OS shutdown signal, shutting down webhook server gracefully...")
-		vaultInjector.Server.Shutdown(context.Background())
+		vaultInjector.Shutdown(webhookParameters.ShutdownDelay, webhookParameters.ShutdownTimeout)
+		close(stopCh)
 		metricsServer.Shutdown(context.Background())
+		vaultInjector.AuditLogger.Close()
+		shutdownTracing(context.Background())
 	}
 }
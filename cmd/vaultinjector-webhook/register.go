@@ -0,0 +1,123 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"talend/vault-sidecar-injector/pkg/k8s"
+	"time"
+
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+func registerWebhook() error {
+	reg, err := webhookRegistration()
+	if err != nil {
+		klog.Errorf("Invalid registration parameters: %v", err)
+		return err
+	}
+
+	k8sctl, err := k8s.New(
+		&k8sClientConfig,
+		&k8s.WebhookData{
+			WebhookSecretName: registerParameters.CertSecretName,
+			WebhookCACertName: filepath.Base(registerParameters.CACertFile),
+			WebhookCfgName:    registerParameters.WebhookCfgName,
+		})
+	if err != nil {
+		return err
+	}
+
+	if reg.CABundle, err = k8sctl.CABundle(registerParameters.CACertFile); err != nil {
+		return err
+	}
+
+	return k8sctl.RegisterWebhook(reg)
+}
+
+func unregisterWebhook() error {
+	k8sctl, err := k8s.New(
+		&k8sClientConfig,
+		&k8s.WebhookData{
+			WebhookCfgName: registerParameters.WebhookCfgName,
+		})
+	if err != nil {
+		return err
+	}
+
+	return k8sctl.UnregisterWebhook()
+}
+
+// Build webhook entry of MutatingWebhookConfiguration from registration flags
+func webhookRegistration() (*k8s.WebhookRegistration, error) {
+	reg := &k8s.WebhookRegistration{
+		ServiceName:        registerParameters.ServiceName,
+		ServiceNamespace:   registerParameters.ServiceNamespace,
+		ServicePort:        int32(registerParameters.ServicePort),
+		Path:               registerParameters.Path,
+		FailurePolicy:      admregv1.FailurePolicyType(registerParameters.FailurePolicy),
+		TimeoutSeconds:     int32(registerParameters.Timeout / time.Second),
+		ReinvocationPolicy: admregv1.ReinvocationPolicyType(registerParameters.ReinvocationPolicy),
+	}
+
+	for _, operation := range strings.Split(registerParameters.Operations, ",") {
+		switch op := admregv1.OperationType(strings.ToUpper(strings.TrimSpace(operation))); op {
+		case admregv1.Create, admregv1.Update, admregv1.Delete, admregv1.Connect, admregv1.OperationAll:
+			reg.Operations = append(reg.Operations, op)
+		default:
+			return nil, fmt.Errorf("Unsupported operation '%s'", operation)
+		}
+	}
+
+	if reg.ServicePort < 1 || reg.ServicePort > 65535 {
+		return nil, fmt.Errorf("Invalid service port %d", reg.ServicePort)
+	}
+
+	if !strings.HasPrefix(reg.Path, "/") {
+		return nil, fmt.Errorf("Path '%s' must start with '/'", reg.Path)
+	}
+
+	if reg.FailurePolicy != admregv1.Ignore && reg.FailurePolicy != admregv1.Fail {
+		return nil, fmt.Errorf("Unsupported failure policy '%s'", reg.FailurePolicy)
+	}
+
+	if reg.TimeoutSeconds < 1 || reg.TimeoutSeconds > 30 {
+		return nil, errors.New("Timeout must be between 1s and 30s")
+	}
+
+	if reg.ReinvocationPolicy != admregv1.NeverReinvocationPolicy && reg.ReinvocationPolicy != admregv1.IfNeededReinvocationPolicy {
+		return nil, fmt.Errorf("Unsupported reinvocation policy '%s'", reg.ReinvocationPolicy)
+	}
+
+	var err error
+	if registerParameters.NamespaceSelector != "" {
+		if reg.NamespaceSelector, err = metav1.ParseToLabelSelector(registerParameters.NamespaceSelector); err != nil {
+			return nil, fmt.Errorf("Invalid namespace selector: %v", err)
+		}
+	}
+
+	if registerParameters.ObjectSelector != "" {
+		if reg.ObjectSelector, err = metav1.ParseToLabelSelector(registerParameters.ObjectSelector); err != nil {
+			return nil, fmt.Errorf("Invalid object selector: %v", err)
+		}
+	}
+
+	return reg, nil
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"talend/vault-sidecar-injector/pkg/config"
+	"talend/vault-sidecar-injector/pkg/webhook"
+
+	"github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog"
+)
+
+func render() error {
+	pod, err := loadPod(renderParameters.ManifestFile)
+	if err != nil {
+		klog.Errorf("Failed to load manifest: %v", err)
+		return err
+	}
+
+	// Load webhook admission server's config
+	vsiCfg, err := config.Load(webhookParameters)
+	if err != nil {
+		return err
+	}
+
+	patch, patchedPod, err := webhook.New(vsiCfg, nil).Render(pod)
+	if err != nil {
+		klog.Errorf("Failed to render manifest: %v", err)
+		return err
+	}
+
+	// Output is a YAML stream: JSON Patch first, then mutated pod
+	var patchOut bytes.Buffer
+	if patch == nil {
+		patch = []byte("[]")
+	}
+
+	if err = json.Indent(&patchOut, patch, "", "  "); err != nil {
+		return err
+	}
+
+	podOut, err := yaml.Marshal(patchedPod)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# JSON Patch\n%s\n---\n# Mutated pod\n%s", patchOut.String(), string(podOut))
+	return nil
+}
+
+func loadPod(manifestFile string) (*corev1.Pod, error) {
+	var data []byte
+	var err error
+
+	if manifestFile == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(manifestFile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pod := &corev1.Pod{}
+	pod.APIVersion = "v1"
+	pod.Kind = "Pod"
+
+	switch resource := obj.(type) {
+	case *corev1.Pod:
+		resource.DeepCopyInto(pod)
+	case *appsv1.Deployment:
+		pod.ObjectMeta = *resource.Spec.Template.ObjectMeta.DeepCopy()
+		pod.Spec = *resource.Spec.Template.Spec.DeepCopy()
+		pod.Namespace = resource.Namespace
+		pod.GenerateName = resource.Name + "-"
+	case *batchv1.Job:
+		pod.ObjectMeta = *resource.Spec.Template.ObjectMeta.DeepCopy()
+		pod.Spec = *resource.Spec.Template.Spec.DeepCopy()
+		pod.Namespace = resource.Namespace
+		pod.GenerateName = resource.Name + "-"
+	default:
+		return nil, fmt.Errorf("Unsupported resource type %T: expect Deployment, Job or Pod", obj)
+	}
+
+	return pod, nil
+}
@@ -16,43 +16,293 @@ package main
 
 import (
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"talend/vault-sidecar-injector/pkg/audit"
+	"talend/vault-sidecar-injector/pkg/certs"
 	"talend/vault-sidecar-injector/pkg/config"
 	"talend/vault-sidecar-injector/pkg/k8s"
+	"talend/vault-sidecar-injector/pkg/watcher"
 	"talend/vault-sidecar-injector/pkg/webhook"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/klog"
 )
 
-func createVaultInjector() (*webhook.VaultInjector, error) {
+func createVaultInjector(k8sctl *k8s.K8SClient) (*webhook.VaultInjector, *certs.KeyPairReloader, error) {
 	// Patch MutatingWebhookConfiguration resource with CA certificate from mounted secret (set 'caBundle' attribute from Webhook CA)
-	err := k8s.New(
-		&k8s.WebhookData{
-			WebhookCfgName: webhookParameters.WebhookCfgName,
-		}).PatchWebhookConfiguration(webhookParameters.CACertFile)
+	if err := k8sctl.PatchWebhookConfiguration(webhookParameters.CACertFile); err != nil {
+		return nil, nil, err
+	}
+
+	// Load TLS cert and key from mounted secret (served through GetCertificate callback to allow reloading)
+	keyPairReloader, err := certs.NewKeyPairReloader(webhookParameters.CertFile, webhookParameters.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vaultInjector, err := newVaultInjector(fmt.Sprintf(":%v", webhookParameters.Port), keyPairReloader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Emit Kubernetes Events when injection is denied
+	vaultInjector.EventRecorder = k8sctl.NewEventRecorder("vault-sidecar-injector")
+
+	return vaultInjector, keyPairReloader, nil
+}
+
+// Set up webhook served to Kubernetes API server, and its background tasks
+func startVaultInjector(stopCh <-chan struct{}) (*webhook.VaultInjector, error) {
+	// Create or update MutatingWebhookConfiguration (optional), before patching its 'caBundle'
+	if webhookParameters.Register {
+		registerParameters.WebhookCfgName = webhookParameters.WebhookCfgName
+		registerParameters.CertSecretName = webhookParameters.CertSecretName
+		registerParameters.CACertFile = webhookParameters.CACertFile
+		if err := registerWebhook(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Kubernetes client shared by webhook's components
+	k8sctl, err := k8sClient()
 	if err != nil {
 		return nil, err
 	}
 
-	// Load TLS cert and key from mounted secret
-	tlsCert, err := tls.LoadX509KeyPair(webhookParameters.CertFile, webhookParameters.KeyFile)
+	// Init and load config
+	vaultInjector, keyPairReloader, err := createVaultInjector(k8sctl)
 	if err != nil {
-		klog.Errorf("Failed to load key pair: %v", err)
 		return nil, err
 	}
 
-	// Load webhook admission server's config
+	// Reload certificates on change, rotate them and keep 'caBundle' in sync
+	caBundleReconciler := reconcileCABundle(k8sctl, stopCh)
+	rotateCerts(k8sctl, stopCh)
+	watchCerts(k8sctl, keyPairReloader, caBundleReconciler, stopCh)
+
+	// Cache namespaces to apply their default annotations
+	if vaultInjector.NamespaceLister, err = k8sctl.NewNamespaceLister(stopCh); err != nil {
+		return nil, err
+	}
+
+	return vaultInjector, nil
+}
+
+// Local development: webhook served on localhost with certificates generated in memory, without any Kubernetes cluster
+func createDevVaultInjector() (*webhook.VaultInjector, error) {
+	bundle, err := (&certs.Cert{
+		CN:           "Vault Sidecar Injector",
+		Hosts:        []string{"localhost", "127.0.0.1"},
+		CALifetime:   24 * time.Hour,
+		KeyAlgorithm: webhookParameters.KeyAlgorithm,
+	}).GenerateWebhookBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := certs.NewStaticKeyPair(bundle.Cert, bundle.PrivKey)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultInjector, err := newVaultInjector(fmt.Sprintf("localhost:%v", webhookParameters.Port), keyPair)
+	if err != nil {
+		return nil, err
+	}
+
+	// CA certificate written to a file only to ease calls with curl
+	caCertFile := filepath.Join(os.TempDir(), "vsi-dev-ca.crt")
+	if err = ioutil.WriteFile(caCertFile, bundle.CACert, 0644); err != nil {
+		klog.Errorf("Failed to write CA certificate file: %v", err)
+		return nil, err
+	}
+
+	fmt.Printf("Webhook running in development mode: no Kubernetes cluster, certificates generated in memory\n\n")
+	fmt.Printf("CA certificate (also written to %s):\n%s\n", caCertFile, bundle.CACert)
+	fmt.Printf("caBundle: %s\n\n", base64.StdEncoding.EncodeToString(bundle.CACert))
+	fmt.Printf("Post an AdmissionReview with:\n  curl --cacert %s -H 'Content-Type: application/json' --data @admissionreview.json https://localhost:%v/mutate\n\n", caCertFile, webhookParameters.Port)
+
+	return vaultInjector, nil
+}
+
+// Create webhook admission server serving provided key pair, with loaded config
+func newVaultInjector(addr string, keyPairReloader *certs.KeyPairReloader) (*webhook.VaultInjector, error) {
 	vsiCfg, err := config.Load(webhookParameters)
 	if err != nil {
 		return nil, err
 	}
 
-	return webhook.New(
+	vaultInjector := webhook.New(
 		vsiCfg,
 		&http.Server{
-			Addr:      fmt.Sprintf(":%v", webhookParameters.Port),
-			TLSConfig: &tls.Config{Certificates: []tls.Certificate{tlsCert}},
+			Addr:         addr,
+			TLSConfig:    &tls.Config{GetCertificate: keyPairReloader.GetCertificate},
+			ReadTimeout:  webhookParameters.ReadTimeout,
+			WriteTimeout: webhookParameters.WriteTimeout,
+			IdleTimeout:  webhookParameters.IdleTimeout,
+		},
+	)
+	vaultInjector.CertExpiryThreshold = webhookParameters.CertExpiryThreshold
+	vaultInjector.MaxRequestBodySize = webhookParameters.MaxRequestBodySize
+	vaultInjector.SetMaxConcurrentRequests(webhookParameters.MaxConcurrentRequests)
+
+	// Audit admission decisions if requested
+	if webhookParameters.AuditLog != "" {
+		if vaultInjector.AuditLogger, err = audit.New(webhookParameters.AuditLog); err != nil {
+			return nil, err
+		}
+	}
+
+	return vaultInjector, nil
+}
+
+// Metrics are only served on localhost in development mode
+func metricsHost() string {
+	if webhookParameters.Dev {
+		return "localhost"
+	}
+
+	return ""
+}
+
+func k8sClient() (*k8s.K8SClient, error) {
+	return k8s.New(
+		&k8sClientConfig,
+		&k8s.WebhookData{
+			WebhookSecretName: webhookParameters.CertSecretName,
+			WebhookCACertName: filepath.Base(webhookParameters.CACertFile),
+			WebhookCertName:   filepath.Base(webhookParameters.CertFile),
+			WebhookKeyName:    filepath.Base(webhookParameters.KeyFile),
+			WebhookCAKeyName:  webhookParameters.CAKeyFile,
+			WebhookCfgName:    webhookParameters.WebhookCfgName,
+		})
+}
+
+// Watch configuration files: load new config on change (previous one is kept if new config cannot be loaded)
+func watchConfig(vaultInjector *webhook.VaultInjector, stopCh <-chan struct{}) {
+	if webhookParameters.ConfigWatchInterval <= 0 {
+		return
+	}
+
+	configFiles := []string{
+		webhookParameters.InjectionCfgFile,
+		webhookParameters.ProxyCfgFile,
+		webhookParameters.TemplateBlockFile,
+		webhookParameters.TemplateDefaultFile,
+		webhookParameters.PodLifecycleHooksFile,
+	}
+
+	if webhookParameters.PolicyFile != "" {
+		configFiles = append(configFiles, webhookParameters.PolicyFile)
+	}
+
+	watcher.New(
+		configFiles,
+		webhookParameters.ConfigWatchInterval,
+		func() {
+			vsiCfg, err := config.Load(webhookParameters)
+			if err != nil {
+				klog.Errorf("Failed to load new configuration, keep previous one [sha256sum: %s]", vaultInjector.Config().Hash)
+				return
+			}
+
+			vaultInjector.ReloadConfig(vsiCfg)
+		}).Start(stopCh)
+}
+
+// Keep webhook configurations' 'caBundle' in sync with CA certificate, even if modified by someone else. Only one replica (holding the Lease) does it.
+func reconcileCABundle(k8sctl *k8s.K8SClient, stopCh <-chan struct{}) *k8s.CABundleReconciler {
+	if !webhookParameters.CABundleReconcile {
+		return nil
+	}
+
+	caBundleReconciler := k8sctl.NewCABundleReconciler(webhookParameters.CACertFile)
+	k8sctl.RunWithLeaderElection(stopCh, leaseNamespace(k8sctl), webhookParameters.WebhookCfgName+"-cabundle", leaseIdentity(), caBundleReconciler.Run)
+
+	return caBundleReconciler
+}
+
+// Rotate webhook certificates stored in Kubernetes secret when due. Only one replica (holding the Lease) does it.
+func rotateCerts(k8sctl *k8s.K8SClient, stopCh <-chan struct{}) {
+	if !webhookParameters.CertRotation {
+		return
+	}
+
+	if webhookParameters.CertSecretName == "" {
+		klog.Warning("Webhook certificates rotation requires name of Kubernetes secret storing them: rotation disabled")
+		return
+	}
+
+	certRotator := k8sctl.NewCertRotator(
+		&certs.Cert{
+			CALifetime:   webhookParameters.CALifetime,
+			LeafLifetime: webhookParameters.LeafLifetime,
+			KeyAlgorithm: webhookParameters.KeyAlgorithm,
+		},
+		certs.RotationOptions{
+			RenewFraction: webhookParameters.CertRotationFraction,
+			Overlap:       webhookParameters.CertRotationOverlap,
+		},
+		webhookParameters.CertRotationInterval)
+	k8sctl.RunWithLeaderElection(stopCh, leaseNamespace(k8sctl), webhookParameters.WebhookCfgName+"-certrotation", leaseIdentity(), certRotator.Run)
+}
+
+func leaseNamespace(k8sctl *k8s.K8SClient) string {
+	if webhookParameters.LeaseNamespace != "" {
+		return webhookParameters.LeaseNamespace
+	}
+
+	ns, err := k8sctl.CurrentNamespace()
+	if err != nil {
+		return metav1.NamespaceDefault
+	}
+
+	return ns
+}
+
+func leaseIdentity() string {
+	identity, err := os.Hostname() // Pod's name
+	if err != nil {
+		klog.Warningf("Failed to get hostname, use random identity for leader election: %v", err)
+		identity = string(uuid.NewUUID())
+	}
+
+	return identity
+}
+
+// Watch webhook certificate files: serve new key pair on change and keep MutatingWebhookConfiguration's 'caBundle' in sync with CA certificate
+func watchCerts(k8sctl *k8s.K8SClient, keyPairReloader *certs.KeyPairReloader, caBundleReconciler *k8s.CABundleReconciler, stopCh <-chan struct{}) {
+	if webhookParameters.CertWatchInterval <= 0 {
+		return
+	}
+
+	watcher.New(
+		[]string{
+			webhookParameters.CertFile,
+			webhookParameters.KeyFile,
+		},
+		webhookParameters.CertWatchInterval,
+		func() {
+			keyPairReloader.Reload()
+		}).Start(stopCh)
+
+	watcher.New(
+		[]string{
+			webhookParameters.CACertFile,
 		},
-	), nil
+		webhookParameters.CertWatchInterval,
+		func() {
+			if caBundleReconciler != nil {
+				caBundleReconciler.Trigger()
+			} else {
+				k8sctl.PatchWebhookConfiguration(webhookParameters.CACertFile)
+			}
+		}).Start(stopCh)
 }
@@ -0,0 +1,69 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// New returns a logger writing audit records to provided file (or standard output if OutputStdout)
+func New(output string) (*Logger, error) {
+	if output == OutputStdout {
+		return &Logger{out: os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		klog.Errorf("Failed to open audit log file: %v", err)
+		return nil, err
+	}
+
+	return &Logger{out: f}, nil
+}
+
+// Log writes audit record. Nothing is done on nil logger (audit disabled).
+func (l *Logger) Log(record *Record) {
+	if l == nil {
+		return
+	}
+
+	record.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		klog.Errorf("Failed to marshal audit record: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err = l.out.Write(append(line, '\n')); err != nil {
+		klog.Errorf("Failed to write audit record: %v", err)
+	}
+}
+
+// Close audit log (standard output is left open)
+func (l *Logger) Close() error {
+	if l == nil || l.out == os.Stdout {
+		return nil
+	}
+
+	return l.out.Close()
+}
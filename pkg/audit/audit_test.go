@@ -0,0 +1,88 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	auditFile := filepath.Join(dir, "audit.log")
+
+	logger, err := New(auditFile)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+
+	logger.Log(&Record{
+		UID:                "1234",
+		Namespace:          "default",
+		PodName:            "test-app-",
+		Owner:              &Owner{Kind: "ReplicaSet", Name: "test-app-5d4f8"},
+		RequestedModes:     []string{"secrets"},
+		EffectiveModes:     []string{"secrets"},
+		VaultRole:          "test",
+		VaultAuthMethod:    "kubernetes",
+		SecretsPaths:       []string{"secret/test/test-svc"},
+		InjectedContainers: []string{"tvsi-vault-agent-init", "tvsi-vault-agent"},
+		Decision:           DecisionInjected,
+	})
+	logger.Log(&Record{UID: "5678", Namespace: "default", PodName: "test-app", Decision: DecisionDenied, Error: "Submitted pod must contain label com.talend.application"})
+	assert.NoError(t, logger.Close())
+
+	f, err := os.Open(auditFile)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	if assert.Len(t, records, 2) {
+		assert.NotEmpty(t, records[0].Timestamp)
+		assert.Equal(t, "ReplicaSet", records[0].Owner.Kind)
+		assert.Equal(t, []string{"secret/test/test-svc"}, records[0].SecretsPaths)
+		assert.Equal(t, DecisionInjected, records[0].Decision)
+		assert.Nil(t, records[1].Owner)
+		assert.Equal(t, DecisionDenied, records[1].Decision)
+		assert.NotEmpty(t, records[1].Error)
+	}
+}
+
+func TestLogDisabled(t *testing.T) {
+	var logger *Logger
+
+	assert.NotPanics(t, func() { logger.Log(&Record{UID: "1234"}) })
+	assert.NoError(t, logger.Close())
+}
@@ -0,0 +1,50 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"io"
+	"sync"
+)
+
+// Logger writes audit records as JSON lines
+type Logger struct {
+	mu  sync.Mutex
+	out io.WriteCloser
+}
+
+// Record of an admission decision
+type Record struct {
+	Timestamp          string   `json:"timestamp"`
+	UID                string   `json:"uid"`
+	Namespace          string   `json:"namespace"`
+	PodName            string   `json:"podName"` // pod name or generateName
+	Owner              *Owner   `json:"owner,omitempty"`
+	RequestedModes     []string `json:"requestedModes,omitempty"`
+	EffectiveModes     []string `json:"effectiveModes,omitempty"`
+	VaultRole          string   `json:"vaultRole,omitempty"`
+	VaultAuthMethod    string   `json:"vaultAuthMethod,omitempty"`
+	SecretsPaths       []string `json:"secretsPaths,omitempty"`
+	InjectedContainers []string `json:"injectedContainers,omitempty"`
+	Decision           string   `json:"decision"`
+	Reason             string   `json:"reason,omitempty"` // why injection was skipped or denied
+	Error              string   `json:"error,omitempty"`
+}
+
+// Owner : controller owning the pod
+type Owner struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
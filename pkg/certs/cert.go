@@ -16,35 +16,58 @@ package certs
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"math/big"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/klog"
 )
 
-// GenerateWebhookBundle generates and returns CA, webhook certificate and private key
+// GenerateWebhookBundle generates and returns CA, webhook certificate and private key. Provided CA, if any, is used instead of generating a self-signed one.
 func (c *Cert) GenerateWebhookBundle() (*PEMBundle, error) {
+	var caBundle *PEMBundle
+	var err error
+
 	// CA
-	_, err := c.genCertificate(true)
-	if err != nil {
-		klog.Errorf("Failed to generate CA certificate: %s", err)
-		return nil, err
+	if len(c.SigningCACert) > 0 {
+		caBundle, err = c.loadCA(c.SigningCACert, c.SigningCAKey)
+		if err != nil {
+			klog.Errorf("Failed to load provided CA: %s", err)
+			return nil, err
+		}
+	} else {
+		caBundle, err = c.genCertificate(true, c.KeyAlgorithm)
+		if err != nil {
+			klog.Errorf("Failed to generate CA certificate: %s", err)
+			return nil, err
+		}
 	}
 
 	// Webhook certificate
-	webhookBundle, err := c.genCertificate(false)
+	webhookBundle, err := c.genCertificate(false, c.KeyAlgorithm)
 	if err != nil {
 		klog.Errorf("Failed to generate webhook certificate: %s", err)
 		return nil, err
 	}
 
+	// Private key of a provided CA is not part of the bundle (and not stored with webhook certificate) unless explicitly requested
+	if len(c.SigningCACert) == 0 || c.StoreSigningCAKey {
+		webhookBundle.CAPrivKey = caBundle.PrivKey
+	}
+
 	if klog.V(5) { // enabled by providing '-v=5' at least
 		klog.Infof("Generated Webhook CA Certificate:\n%s\n", string(webhookBundle.CACert))
 		klog.Infof("Generated Webhook Certificate:\n%s\n", string(webhookBundle.Cert))
@@ -53,14 +76,54 @@ func (c *Cert) GenerateWebhookBundle() (*PEMBundle, error) {
 	return webhookBundle, nil
 }
 
-func (c *Cert) genCertificate(isCA bool) (*PEMBundle, error) {
+// loadCA sets provided CA (certificate and private key) as the one issuing webhook certificates
+func (c *Cert) loadCA(pemCACert, pemCAKey []byte) (*PEMBundle, error) {
+	caCerts, err := parseCerts(pemCACert)
+	if err != nil {
+		return nil, err
+	}
+
+	caPrivKey, err := parsePrivateKey(pemCAKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// First certificate is the issuing CA, others (if any) are part of its chain
+	caCert := caCerts[0]
+	if !caCert.IsCA {
+		return nil, errors.New("Provided certificate is not a CA certificate")
+	}
+
+	if !publicKeyEqual(caCert.PublicKey, caPrivKey.Public()) {
+		return nil, errors.New("Provided CA private key does not match CA certificate")
+	}
+
+	c.caTemplate = caCert
+	c.caPrivKey = caPrivKey
+	c.caCert = pemCACert
+
+	return &PEMBundle{CACert: pemCACert, Cert: pemCACert, PrivKey: pemCAKey}, nil
+}
+
+func (c *Cert) genCertificate(isCA bool, keyAlgorithm string) (*PEMBundle, error) {
 	var derBytes []byte
 	var certBnd PEMBundle
 	var keyUsage x509.KeyUsage
 
 	cn := c.CN
 	notBefore := time.Now().Add(time.Minute * -5)
-	notAfter := notBefore.Add(time.Duration(c.Lifetime) * 365 * 24 * time.Hour)
+	notAfter := notBefore.Add(c.CALifetime)
+
+	if !isCA {
+		if c.LeafLifetime > 0 {
+			notAfter = notBefore.Add(c.LeafLifetime)
+		}
+
+		// Webhook certificate cannot outlive its CA
+		if notAfter.After(c.caTemplate.NotAfter) {
+			notAfter = c.caTemplate.NotAfter
+		}
+	}
 
 	sn, err := serialNumber()
 	if err != nil {
@@ -72,7 +135,10 @@ func (c *Cert) genCertificate(isCA bool) (*PEMBundle, error) {
 		cn = cn + " CA"
 		keyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
 	} else {
-		keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		keyUsage = x509.KeyUsageDigitalSignature
+		if keyAlgorithm != KeyAlgorithmEd25519 { // No key encipherment with EdDSA
+			keyUsage |= x509.KeyUsageKeyEncipherment
+		}
 	}
 
 	template := x509.Certificate{
@@ -87,15 +153,15 @@ func (c *Cert) genCertificate(isCA bool) (*PEMBundle, error) {
 	}
 
 	//-- Generate key pair
-	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key, err := generateKey(keyAlgorithm)
 	if err != nil {
-		klog.Errorf("Failed ECDSA Key Generation: %s", err)
+		klog.Errorf("Failed %s Key Generation: %s", keyAlgorithm, err)
 		return nil, err
 	}
 
 	//-- Generate certificate
 	if isCA { // Self-signed
-		derBytes, err = x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+		derBytes, err = x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
 		c.caTemplate = &template
 		c.caPrivKey = key
 	} else {
@@ -107,7 +173,7 @@ func (c *Cert) genCertificate(isCA bool) (*PEMBundle, error) {
 			}
 		}
 
-		derBytes, err = x509.CreateCertificate(rand.Reader, &template, c.caTemplate, &key.PublicKey, c.caPrivKey)
+		derBytes, err = x509.CreateCertificate(rand.Reader, &template, c.caTemplate, key.Public(), c.caPrivKey)
 	}
 
 	if err != nil {
@@ -138,18 +204,97 @@ func (c *Cert) genCertificate(isCA bool) (*PEMBundle, error) {
 	return &certBnd, nil
 }
 
+func generateKey(keyAlgorithm string) (crypto.Signer, error) {
+	switch keyAlgorithm {
+	case KeyAlgorithmECDSAP256, "":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyAlgorithmRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyAlgorithmRSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case KeyAlgorithmRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("Unsupported key algorithm '%s'", keyAlgorithm)
+	}
+}
+
+// keyAlgorithmOf returns algorithm of provided public key (see KeyAlgorithm* constants), empty if not supported
+func keyAlgorithmOf(pubKey crypto.PublicKey) string {
+	switch key := pubKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return KeyAlgorithmECDSAP256
+		case elliptic.P384():
+			return KeyAlgorithmECDSAP384
+		}
+	case *rsa.PublicKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return KeyAlgorithmRSA2048
+		case 3072:
+			return KeyAlgorithmRSA3072
+		case 4096:
+			return KeyAlgorithmRSA4096
+		}
+	case ed25519.PublicKey:
+		return KeyAlgorithmEd25519
+	}
+
+	return ""
+}
+
+func publicKeyEqual(pubKey, otherPubKey crypto.PublicKey) bool {
+	key, ok := pubKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(otherPubKey)
+}
+
+// ParseLifetime parses a lifetime expressed in days (e.g. '30d') or as a duration (e.g. '720h', see time.ParseDuration)
+func ParseLifetime(lifetime string) (time.Duration, error) {
+	if days := strings.TrimSuffix(lifetime, "d"); days != lifetime {
+		nbDays, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid lifetime '%s': %v", lifetime, err)
+		}
+
+		return time.Duration(nbDays) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(lifetime)
+}
+
 func serialNumber() (*big.Int, error) {
 	return rand.Int(rand.Reader, (&big.Int{}).Exp(big.NewInt(2), big.NewInt(159), nil))
 }
 
-func pemEncodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
-	b, err := x509.MarshalECPrivateKey(key)
-	if err != nil {
-		return nil, err
+func pemEncodeKey(key crypto.Signer) ([]byte, error) {
+	var block *pem.Block
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		b, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	default:
+		b, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: b}
 	}
 
 	var buf bytes.Buffer
-	err = pem.Encode(&buf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+	err := pem.Encode(&buf, block)
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +302,58 @@ func pemEncodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func parsePrivateKey(pemKey []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("No PEM-encoded private key found")
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("Unsupported private key type")
+		}
+
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("Unsupported PEM block type for private key: %s", block.Type)
+	}
+}
+
+// parseCerts returns all certificates found in PEM-encoded content
+func parseCerts(pemCerts []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for block, rest := pem.Decode(pemCerts); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("No PEM-encoded certificate found")
+	}
+
+	return certs, nil
+}
+
 func pemEncodeCert(derBytes []byte) ([]byte, error) {
 	var buf bytes.Buffer
 	err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
@@ -15,13 +15,20 @@
 package certs
 
 import (
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"k8s.io/klog"
 )
 
@@ -35,9 +42,9 @@ func TestCert(t *testing.T) {
 	}
 
 	cert := &Cert{
-		CN:       "Vault Sidecar Injector",
-		Hosts:    strings.Split("vault-sidecar-injector,vault-sidecar-injector.default,vault-sidecar-injector.default.svc", ","),
-		Lifetime: 10,
+		CN:         "Vault Sidecar Injector",
+		Hosts:      strings.Split("vault-sidecar-injector,vault-sidecar-injector.default,vault-sidecar-injector.default.svc", ","),
+		CALifetime: 10 * 365 * 24 * time.Hour,
 	}
 
 	bundle, err := cert.GenerateWebhookBundle()
@@ -52,3 +59,203 @@ func TestCert(t *testing.T) {
 
 	klog.Infof("tlsCert=%+v", tlsCert)
 }
+
+func TestKeyPairReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	writeBundle := func() *PEMBundle {
+		bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 24 * time.Hour}).GenerateWebhookBundle()
+		if err != nil {
+			t.Fatalf("Failed to generate webhook bundle: %v", err)
+		}
+
+		if err = ioutil.WriteFile(certFile, bundle.Cert, 0644); err != nil {
+			t.Fatalf("Failed to write certificate: %v", err)
+		}
+
+		if err = ioutil.WriteFile(keyFile, bundle.PrivKey, 0600); err != nil {
+			t.Fatalf("Failed to write private key: %v", err)
+		}
+
+		return bundle
+	}
+
+	bundle := writeBundle()
+
+	reloader, err := NewKeyPairReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load key pair: %v", err)
+	}
+
+	assertServedCert := func(bundle *PEMBundle) {
+		tlsCert, _ := reloader.GetCertificate(nil)
+		expectedCert, _ := tls.X509KeyPair(bundle.Cert, bundle.PrivKey)
+		assert.Equal(t, expectedCert.Certificate, tlsCert.Certificate)
+	}
+
+	assertServedCert(bundle)
+
+	// New key pair
+	newBundle := writeBundle()
+	assert.NoError(t, reloader.Reload())
+	assertServedCert(newBundle)
+
+	// Invalid key pair: keep previous one
+	if err = ioutil.WriteFile(keyFile, bundle.PrivKey, 0600); err != nil {
+		t.Fatalf("Failed to write private key: %v", err)
+	}
+
+	assert.Error(t, reloader.Reload())
+	assertServedCert(newBundle)
+}
+
+func TestStaticKeyPair(t *testing.T) {
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 24 * time.Hour}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	reloader, err := NewStaticKeyPair(bundle.Cert, bundle.PrivKey)
+	if assert.NoError(t, err) {
+		tlsCert, _ := reloader.GetCertificate(nil)
+		expectedCert, _ := tls.X509KeyPair(bundle.Cert, bundle.PrivKey)
+		assert.Equal(t, expectedCert.Certificate, tlsCert.Certificate)
+	}
+
+	// Mismatching private key
+	_, err = NewStaticKeyPair(bundle.Cert, bundle.CAPrivKey)
+	assert.Error(t, err)
+}
+
+func TestKeyAlgorithms(t *testing.T) {
+	for _, keyAlgorithm := range []string{KeyAlgorithmECDSAP256, KeyAlgorithmECDSAP384, KeyAlgorithmRSA2048, KeyAlgorithmRSA3072, KeyAlgorithmEd25519} {
+		t.Run(keyAlgorithm, func(t *testing.T) {
+			bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 24 * time.Hour, KeyAlgorithm: keyAlgorithm}).GenerateWebhookBundle()
+			if err != nil {
+				t.Fatalf("Failed to generate webhook bundle: %v", err)
+			}
+
+			_, err = tls.X509KeyPair(bundle.Cert, bundle.PrivKey)
+			assert.NoError(t, err)
+
+			for _, pemCert := range [][]byte{bundle.CACert, bundle.Cert} {
+				certs, err := parseCerts(pemCert)
+				if assert.NoError(t, err) {
+					assert.Equal(t, keyAlgorithm, keyAlgorithmOf(certs[0].PublicKey))
+				}
+			}
+
+			// CA private key can be loaded back
+			caKey, err := parsePrivateKey(bundle.CAPrivKey)
+			if assert.NoError(t, err) {
+				caCerts, _ := parseCerts(bundle.CACert)
+				assert.True(t, publicKeyEqual(caCerts[0].PublicKey, caKey.Public()))
+			}
+		})
+	}
+
+	_, err := (&Cert{CN: "Vault Sidecar Injector", CALifetime: time.Hour, KeyAlgorithm: "dsa-1024"}).GenerateWebhookBundle()
+	assert.Error(t, err)
+}
+
+// newIntermediateCA returns an intermediate CA issued by a self-signed root CA
+func newIntermediateCA(t *testing.T, lifetime time.Duration) (pemCert, pemKey []byte) {
+	root := &Cert{CN: "Corporate Root", CALifetime: 2 * lifetime}
+	if _, err := root.genCertificate(true, KeyAlgorithmRSA2048); err != nil {
+		t.Fatalf("Failed to generate root CA: %v", err)
+	}
+
+	key, err := generateKey(KeyAlgorithmECDSAP384)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	sn, _ := serialNumber()
+	template := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{CommonName: "Corporate Intermediate CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(lifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, root.caTemplate, key.Public(), root.caPrivKey)
+	if err != nil {
+		t.Fatalf("Failed to generate intermediate CA: %v", err)
+	}
+
+	pemCert, _ = pemEncodeCert(der)
+	pemKey, _ = pemEncodeKey(key)
+	return pemCert, pemKey
+}
+
+func TestProvidedCA(t *testing.T) {
+	caCert, caKey := newIntermediateCA(t, 24*time.Hour)
+
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, LeafLifetime: 48 * time.Hour, SigningCACert: caCert, SigningCAKey: caKey}).GenerateWebhookBundle()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, caCert, bundle.CACert)
+	assert.Empty(t, bundle.CAPrivKey) // Provided CA private key not stored unless requested
+
+	leaf, _ := parseCerts(bundle.Cert)
+	ca, _ := parseCerts(caCert)
+	assert.NoError(t, leaf[0].CheckSignatureFrom(ca[0]))
+	assert.Equal(t, "CN=Corporate Intermediate CA", leaf[0].Issuer.String())
+	assert.Equal(t, ca[0].NotAfter, leaf[0].NotAfter) // Cannot outlive provided CA
+
+	// Key not matching CA certificate
+	_, otherKey := newIntermediateCA(t, time.Hour)
+	_, err = (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, SigningCACert: caCert, SigningCAKey: otherKey}).GenerateWebhookBundle()
+	assert.Error(t, err)
+
+	// Not a CA certificate
+	_, err = (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, SigningCACert: bundle.Cert, SigningCAKey: bundle.PrivKey}).GenerateWebhookBundle()
+	assert.Error(t, err)
+
+	// Provided CA private key stored on request, so that webhook certificate can be rotated
+	bundle, err = (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, SigningCACert: caCert, SigningCAKey: caKey, StoreSigningCAKey: true}).GenerateWebhookBundle()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, caKey, bundle.CAPrivKey)
+
+	// Provided CA cannot be rolled, only webhook certificate is rotated
+	_, err = (&Cert{}).Rotate(bundle, RotationOptions{RotateCA: true})
+	assert.Error(t, err)
+
+	rotated, err := (&Cert{}).Rotate(bundle, RotationOptions{Force: true, RenewFraction: 0.0001})
+	if assert.NoError(t, err) && assert.NotNil(t, rotated) {
+		assert.Equal(t, caCert, rotated.CACert)
+		assert.NotEqual(t, bundle.Cert, rotated.Cert)
+	}
+}
+
+func TestParseLifetime(t *testing.T) {
+	for lifetime, expected := range map[string]time.Duration{
+		"30d":   30 * 24 * time.Hour,
+		"0d":    0,
+		"720h":  720 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	} {
+		parsed, err := ParseLifetime(lifetime)
+		assert.NoError(t, err, lifetime)
+		assert.Equal(t, expected, parsed, lifetime)
+	}
+
+	for _, lifetime := range []string{"", "d", "1.5d", "30", "1y"} {
+		_, err := ParseLifetime(lifetime)
+		assert.Error(t, err, lifetime)
+	}
+}
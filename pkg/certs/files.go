@@ -0,0 +1,72 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+)
+
+// WriteFiles writes certificates and private keys of the bundle as PEM files in provided directory. Private keys are only readable by their owner.
+func (bundle *PEMBundle) WriteFiles(dir string, files *BundleFiles) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		klog.Errorf("Failed to create directory %s: %v", dir, err)
+		return err
+	}
+
+	for _, file := range []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{files.CACert, bundle.CACert, 0644},
+		{files.Cert, bundle.Cert, 0644},
+		{files.PrivKey, bundle.PrivKey, 0600},
+		{files.CAPrivKey, bundle.CAPrivKey, 0600},
+	} {
+		if file.name == "" || len(file.data) == 0 {
+			continue
+		}
+
+		if err := writeFile(filepath.Join(dir, file.name), file.data, file.perm); err != nil {
+			klog.Errorf("Failed to write %s: %v", file.name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write file with provided permissions, even if it already exists (content is never readable with wider permissions)
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if err = f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,72 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFiles(t *testing.T) {
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 24 * time.Hour}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "certs")
+
+	// Existing key file with wider permissions
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "tls.key"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := &BundleFiles{CACert: "ca.crt", Cert: "tls.crt", PrivKey: "tls.key", CAPrivKey: "ca.key"}
+	assert.NoError(t, bundle.WriteFiles(dir, files))
+
+	for name, expected := range map[string]struct {
+		data []byte
+		perm os.FileMode
+	}{
+		"ca.crt":  {bundle.CACert, 0644},
+		"tls.crt": {bundle.Cert, 0644},
+		"tls.key": {bundle.PrivKey, 0600},
+		"ca.key":  {bundle.CAPrivKey, 0600},
+	} {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, expected.data, data, name)
+		}
+
+		info, err := os.Stat(path)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, expected.perm, info.Mode().Perm(), name)
+		}
+	}
+
+	// CA private key not written if no file name
+	dir = t.TempDir()
+	files.CAPrivKey = ""
+	assert.NoError(t, bundle.WriteFiles(dir, files))
+	_, err = os.Stat(filepath.Join(dir, "ca.key"))
+	assert.True(t, os.IsNotExist(err))
+}
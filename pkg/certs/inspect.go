@@ -0,0 +1,74 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Inspect returns description of all certificates found in PEM-encoded content
+func Inspect(pemCerts []byte) ([]*CertInfo, error) {
+	certs, err := parseCerts(pemCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*CertInfo, 0, len(certs))
+	for _, cert := range certs {
+		serial := fmt.Sprintf("%X", cert.SerialNumber)
+		if len(serial)%2 != 0 {
+			serial = "0" + serial
+		}
+
+		var serialBytes []string
+		for i := 0; i < len(serial); i += 2 {
+			serialBytes = append(serialBytes, serial[i:i+2])
+		}
+
+		infos = append(infos, &CertInfo{
+			Subject:      cert.Subject.String(),
+			SANs:         hosts(cert),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: strings.Join(serialBytes, ":"),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			ExpiresIn:    time.Until(cert.NotAfter).Round(time.Second).String(),
+			KeyAlgorithm: keyAlgorithm(cert.PublicKey),
+			IsCA:         cert.IsCA,
+		})
+	}
+
+	return infos, nil
+}
+
+// keyAlgorithm returns key type and size of public key (e.g. 'ECDSA P-256', 'RSA 3072', 'Ed25519')
+func keyAlgorithm(pubKey crypto.PublicKey) string {
+	switch key := pubKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("Unknown (%T)", pubKey)
+	}
+}
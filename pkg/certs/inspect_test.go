@@ -0,0 +1,52 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	bundle := newTestBundle(t, week)
+
+	caInfos, err := Inspect(bundle.CACert)
+	if !assert.NoError(t, err) || !assert.Len(t, caInfos, 1) {
+		return
+	}
+
+	assert.Equal(t, "CN=Vault Sidecar Injector CA", caInfos[0].Subject)
+	assert.Equal(t, caInfos[0].Subject, caInfos[0].Issuer)
+	assert.Equal(t, "ECDSA P-256", caInfos[0].KeyAlgorithm)
+	assert.True(t, caInfos[0].IsCA)
+	assert.Empty(t, caInfos[0].SANs)
+
+	leafInfos, err := Inspect(bundle.Cert)
+	if !assert.NoError(t, err) || !assert.Len(t, leafInfos, 1) {
+		return
+	}
+
+	assert.Equal(t, "CN=Vault Sidecar Injector", leafInfos[0].Subject)
+	assert.Equal(t, "CN=Vault Sidecar Injector CA", leafInfos[0].Issuer)
+	assert.Equal(t, []string{"vault-sidecar-injector.default.svc", "127.0.0.1"}, leafInfos[0].SANs)
+	assert.Regexp(t, "^([0-9A-F]{2}:)*[0-9A-F]{2}$", leafInfos[0].SerialNumber)
+	assert.False(t, leafInfos[0].IsCA)
+	assert.True(t, leafInfos[0].NotAfter.After(leafInfos[0].NotBefore))
+	assert.Regexp(t, "^16[78]h", leafInfos[0].ExpiresIn) // ~7 days
+
+	_, err = Inspect([]byte("not a certificate"))
+	assert.Error(t, err)
+}
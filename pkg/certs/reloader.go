@@ -0,0 +1,78 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"talend/vault-sidecar-injector/pkg/metrics"
+
+	"k8s.io/klog"
+)
+
+// NewKeyPairReloader loads webhook certificate and private key from files
+func NewKeyPairReloader(certFile, keyFile string) (*KeyPairReloader, error) {
+	reloader := &KeyPairReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// NewStaticKeyPair serves provided PEM-encoded webhook certificate and private key (e.g. generated in memory). No files to reload from.
+func NewStaticKeyPair(certPEM, keyPEM []byte) (*KeyPairReloader, error) {
+	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		klog.Errorf("Failed to load key pair: %v", err)
+		return nil, err
+	}
+
+	reloader := &KeyPairReloader{}
+	reloader.store(&keyPair)
+
+	return reloader, nil
+}
+
+// Reload loads webhook certificate and private key again. Current key pair is kept on error.
+func (r *KeyPairReloader) Reload() error {
+	keyPair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		klog.Errorf("Failed to load key pair: %v", err)
+		return err
+	}
+
+	r.store(&keyPair)
+	klog.Infof("Loaded key pair from %s and %s", r.certFile, r.keyFile)
+
+	return nil
+}
+
+func (r *KeyPairReloader) store(keyPair *tls.Certificate) {
+	r.keyPair.Store(keyPair)
+
+	if leaf, err := x509.ParseCertificate(keyPair.Certificate[0]); err == nil {
+		metrics.CertificateLoaded(leaf.NotAfter)
+	}
+}
+
+// GetCertificate returns current key pair (to use as tls.Config's GetCertificate callback)
+func (r *KeyPairReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.keyPair.Load().(*tls.Certificate), nil
+}
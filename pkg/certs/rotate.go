@@ -0,0 +1,209 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// Rotate returns a new bundle from current one: webhook certificate is issued again from current CA, or from a new CA if requested or due.
+// Previous CAs are kept in CA bundle during overlap period, so that webhook certificates they issued are still trusted. Returns nil if there is nothing to rotate.
+func (c *Cert) Rotate(current *PEMBundle, opts RotationOptions) (*PEMBundle, error) {
+	now := time.Now()
+
+	caCerts, err := parseCerts(current.CACert)
+	if err != nil {
+		klog.Errorf("Failed to parse CA certificate: %s", err)
+		return nil, err
+	}
+
+	leafCerts, err := parseCerts(current.Cert)
+	if err != nil {
+		klog.Errorf("Failed to parse webhook certificate: %s", err)
+		return nil, err
+	}
+	leaf := leafCerts[0]
+
+	// Look for current CA: the one matching CA private key
+	var caCert *x509.Certificate
+	var caPrivKey crypto.Signer
+	if len(current.CAPrivKey) > 0 {
+		if caPrivKey, err = parsePrivateKey(current.CAPrivKey); err != nil {
+			klog.Errorf("Failed to parse CA private key: %s", err)
+			return nil, err
+		}
+
+		for _, ca := range caCerts {
+			if publicKeyEqual(ca.PublicKey, caPrivKey.Public()) {
+				caCert = ca
+				break
+			}
+		}
+	}
+
+	// Provided CA (e.g. intermediate CA) whose private key is not stored: no new webhook certificate can be issued from it, and it must not
+	// be silently replaced by a self-signed CA
+	if caCert == nil && caCerts[0].CheckSignatureFrom(caCerts[0]) != nil {
+		err := errors.New("CA is not self-signed (provided CA) and no matching CA private key is available: create certificates again from provided CA")
+		klog.Error(err.Error())
+		return nil, err
+	}
+
+	rotateCA := opts.RotateCA || dueForRotation(caCert, opts.RenewFraction, now)
+	if caCert == nil && !rotateCA {
+		klog.Warning("No CA private key matching CA certificate: CA has to be rolled to issue new webhook certificate")
+		rotateCA = true
+	}
+
+	// Provided CA (e.g. intermediate CA) cannot be replaced by a self-signed one
+	if rotateCA && caCert != nil && caCert.CheckSignatureFrom(caCert) != nil {
+		if opts.RotateCA {
+			return nil, errors.New("CA is not self-signed (provided CA): it cannot be rolled, create certificates again from new CA")
+		}
+
+		klog.Warningf("CA is not self-signed (provided CA) and cannot be rolled, provide new CA before it expires on %v", caCert.NotAfter)
+		rotateCA = false
+	}
+
+	rotateLeaf := rotateCA || opts.Force || dueForRotation(leaf, opts.RenewFraction, now) || leaf.CheckSignatureFrom(caCert) != nil
+
+	// Keep previous CAs until they expire or overlap period (starting from current CA's creation) is over. Chain of provided CA is kept as well.
+	var previousCAs []*x509.Certificate
+	for _, ca := range caCerts {
+		if now.After(ca.NotAfter) {
+			continue
+		}
+
+		if !rotateCA && (ca == caCert || (now.After(caCert.NotBefore.Add(opts.Overlap)) && caCert.CheckSignatureFrom(ca) != nil)) {
+			continue
+		}
+
+		previousCAs = append(previousCAs, ca)
+	}
+
+	pruneCA := !rotateCA && len(previousCAs) != len(caCerts)-1
+
+	if !rotateLeaf && !pruneCA {
+		return nil, nil
+	}
+
+	// Reuse names from current webhook certificate if not provided
+	if c.CN == "" {
+		c.CN = leaf.Subject.CommonName
+	}
+
+	if len(c.Hosts) == 0 || (len(c.Hosts) == 1 && c.Hosts[0] == "") {
+		c.Hosts = hosts(leaf)
+	}
+
+	// Keep current lifetimes and key algorithms if not provided
+	currentCA := caCert
+	if currentCA == nil {
+		currentCA = caCerts[0]
+	}
+
+	if c.CALifetime == 0 {
+		c.CALifetime = currentCA.NotAfter.Sub(currentCA.NotBefore)
+	}
+
+	if c.LeafLifetime == 0 {
+		c.LeafLifetime = leaf.NotAfter.Sub(leaf.NotBefore)
+	}
+
+	caKeyAlgorithm, leafKeyAlgorithm := c.KeyAlgorithm, c.KeyAlgorithm
+	if c.KeyAlgorithm == "" {
+		caKeyAlgorithm = keyAlgorithmOf(currentCA.PublicKey)
+		leafKeyAlgorithm = keyAlgorithmOf(leaf.PublicKey)
+	}
+
+	bundle := &PEMBundle{
+		Cert:      current.Cert,
+		PrivKey:   current.PrivKey,
+		CAPrivKey: current.CAPrivKey,
+	}
+
+	if rotateCA {
+		klog.Info("Rolling webhook CA")
+		caBundle, err := c.genCertificate(true, caKeyAlgorithm)
+		if err != nil {
+			klog.Errorf("Failed to generate CA certificate: %s", err)
+			return nil, err
+		}
+
+		bundle.CAPrivKey = caBundle.PrivKey
+	} else {
+		c.caTemplate = caCert
+		c.caPrivKey = caPrivKey
+		if c.caCert, err = pemEncodeCert(caCert.Raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotateLeaf {
+		klog.Infof("Issuing new webhook certificate for %v", c.Hosts)
+		leafBundle, err := c.genCertificate(false, leafKeyAlgorithm)
+		if err != nil {
+			klog.Errorf("Failed to generate webhook certificate: %s", err)
+			return nil, err
+		}
+
+		bundle.Cert = leafBundle.Cert
+		bundle.PrivKey = leafBundle.PrivKey
+	}
+
+	// Current CA first, then previous ones
+	var caBundle bytes.Buffer
+	caBundle.Write(c.caCert)
+	for _, ca := range previousCAs {
+		pemCA, err := pemEncodeCert(ca.Raw)
+		if err != nil {
+			return nil, err
+		}
+
+		caBundle.Write(pemCA)
+	}
+	bundle.CACert = caBundle.Bytes()
+
+	if len(previousCAs) > 0 {
+		klog.Infof("Keeping %d previous CA certificate(s) in CA bundle", len(previousCAs))
+	}
+
+	return bundle, nil
+}
+
+// dueForRotation tells whether provided fraction of certificate's lifetime has elapsed
+func dueForRotation(cert *x509.Certificate, renewFraction float64, now time.Time) bool {
+	if cert == nil || renewFraction <= 0 {
+		return false
+	}
+
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return now.After(cert.NotBefore.Add(time.Duration(float64(lifetime) * renewFraction)))
+}
+
+// hosts returns names and IP addresses registered in certificate
+func hosts(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
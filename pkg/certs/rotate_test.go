@@ -0,0 +1,190 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	week = 7 * 24 * time.Hour
+	year = 365 * 24 * time.Hour
+)
+
+func newTestBundle(t *testing.T, leafLifetime time.Duration) *PEMBundle {
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"vault-sidecar-injector.default.svc", "127.0.0.1"}, CALifetime: year, LeafLifetime: leafLifetime}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	return bundle
+}
+
+func mustParseCerts(t *testing.T, pemCerts []byte) []*x509.Certificate {
+	certs, err := parseCerts(pemCerts)
+	if err != nil {
+		t.Fatalf("Failed to parse certificates: %v", err)
+	}
+
+	return certs
+}
+
+func assertVerifies(t *testing.T, bundle *PEMBundle) {
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(bundle.CACert))
+
+	leaf := mustParseCerts(t, bundle.Cert)[0]
+	_, err := leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "vault-sidecar-injector.default.svc"})
+	assert.NoError(t, err)
+}
+
+func TestGenerateWebhookBundleLeafLifetime(t *testing.T) {
+	bundle := newTestBundle(t, week)
+	assert.NotEmpty(t, bundle.CAPrivKey)
+
+	leaf := mustParseCerts(t, bundle.Cert)[0]
+	assert.Equal(t, week, leaf.NotAfter.Sub(leaf.NotBefore))
+
+	// Webhook certificate cannot outlive its CA
+	bundle = newTestBundle(t, 2*year)
+	ca := mustParseCerts(t, bundle.CACert)[0]
+	leaf = mustParseCerts(t, bundle.Cert)[0]
+	assert.Equal(t, ca.NotAfter, leaf.NotAfter)
+}
+
+func TestRotateLeaf(t *testing.T) {
+	bundle := newTestBundle(t, week)
+
+	// Not due yet
+	rotated, err := (&Cert{LeafLifetime: week}).Rotate(bundle, RotationOptions{RenewFraction: 0.5, Overlap: time.Hour})
+	assert.NoError(t, err)
+	assert.Nil(t, rotated)
+
+	// Forced: same CA, new webhook certificate with same names
+	rotated, err = (&Cert{LeafLifetime: week}).Rotate(bundle, RotationOptions{Force: true, Overlap: time.Hour})
+	if !assert.NoError(t, err) || !assert.NotNil(t, rotated) {
+		return
+	}
+
+	assert.Equal(t, bundle.CACert, rotated.CACert)
+	assert.Equal(t, bundle.CAPrivKey, rotated.CAPrivKey)
+	assert.NotEqual(t, bundle.Cert, rotated.Cert)
+	assert.NotEqual(t, bundle.PrivKey, rotated.PrivKey)
+
+	oldLeaf, newLeaf := mustParseCerts(t, bundle.Cert)[0], mustParseCerts(t, rotated.Cert)[0]
+	assert.Equal(t, oldLeaf.Subject.CommonName, newLeaf.Subject.CommonName)
+	assert.Equal(t, hosts(oldLeaf), hosts(newLeaf))
+	assertVerifies(t, rotated)
+
+	// Due: more than 1% of lifetime has elapsed (certificates are backdated by 5 minutes)
+	rotated, err = (&Cert{LeafLifetime: week}).Rotate(bundle, RotationOptions{RenewFraction: 0.0001})
+	assert.NoError(t, err)
+	assert.NotNil(t, rotated)
+}
+
+func TestRotateCA(t *testing.T) {
+	bundle := newTestBundle(t, week)
+
+	rotated, err := (&Cert{CALifetime: year, LeafLifetime: week}).Rotate(bundle, RotationOptions{RotateCA: true, Overlap: time.Hour})
+	if !assert.NoError(t, err) || !assert.NotNil(t, rotated) {
+		return
+	}
+
+	// New CA first, previous one still trusted during overlap period
+	oldCA := mustParseCerts(t, bundle.CACert)[0]
+	cas := mustParseCerts(t, rotated.CACert)
+	if assert.Len(t, cas, 2) {
+		assert.NotEqual(t, oldCA.Raw, cas[0].Raw)
+		assert.Equal(t, oldCA.Raw, cas[1].Raw)
+	}
+	assert.NotEqual(t, bundle.CAPrivKey, rotated.CAPrivKey)
+	assertVerifies(t, rotated)
+
+	// Previous webhook certificate still trusted
+	assertVerifies(t, &PEMBundle{CACert: rotated.CACert, Cert: bundle.Cert})
+
+	// Nothing to do during overlap period
+	again, err := (&Cert{LeafLifetime: week}).Rotate(rotated, RotationOptions{Overlap: time.Hour})
+	assert.NoError(t, err)
+	assert.Nil(t, again)
+
+	// Previous CA removed once overlap period is over, webhook certificate kept
+	pruned, err := (&Cert{LeafLifetime: week}).Rotate(rotated, RotationOptions{Overlap: 0})
+	if !assert.NoError(t, err) || !assert.NotNil(t, pruned) {
+		return
+	}
+	assert.Len(t, mustParseCerts(t, pruned.CACert), 1)
+	assert.Equal(t, rotated.Cert, pruned.Cert)
+	assertVerifies(t, pruned)
+}
+
+func TestRotateWithoutCAKey(t *testing.T) {
+	bundle := newTestBundle(t, week)
+	bundle.CAPrivKey = nil
+
+	// CA is rolled as webhook certificate cannot be issued by current one
+	rotated, err := (&Cert{CALifetime: year, LeafLifetime: week}).Rotate(bundle, RotationOptions{Overlap: time.Hour})
+	if !assert.NoError(t, err) || !assert.NotNil(t, rotated) {
+		return
+	}
+
+	assert.NotEmpty(t, rotated.CAPrivKey)
+	assert.Len(t, mustParseCerts(t, rotated.CACert), 2)
+	assertVerifies(t, rotated)
+}
+
+func TestRotateProvidedCAWithoutCAKey(t *testing.T) {
+	caCert, caKey := newIntermediateCA(t, year)
+
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, LeafLifetime: week, SigningCACert: caCert, SigningCAKey: caKey}).GenerateWebhookBundle()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// No self-signed CA generated in place of provided one
+	for _, opts := range []RotationOptions{{}, {Force: true}, {RotateCA: true}} {
+		rotated, err := (&Cert{}).Rotate(bundle, opts)
+		assert.Error(t, err)
+		assert.Nil(t, rotated)
+	}
+}
+
+func TestRotateKeepsKeyAlgorithmsAndLifetimes(t *testing.T) {
+	bundle, err := (&Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: year, LeafLifetime: 36 * time.Hour, KeyAlgorithm: KeyAlgorithmEd25519}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	rotated, err := (&Cert{}).Rotate(bundle, RotationOptions{RotateCA: true, Overlap: time.Hour})
+	if !assert.NoError(t, err) || !assert.NotNil(t, rotated) {
+		return
+	}
+
+	ca, leaf := mustParseCerts(t, rotated.CACert)[0], mustParseCerts(t, rotated.Cert)[0]
+	assert.Equal(t, KeyAlgorithmEd25519, keyAlgorithmOf(ca.PublicKey))
+	assert.Equal(t, KeyAlgorithmEd25519, keyAlgorithmOf(leaf.PublicKey))
+	assert.Equal(t, year, ca.NotAfter.Sub(ca.NotBefore))
+	assert.Equal(t, 36*time.Hour, leaf.NotAfter.Sub(leaf.NotBefore))
+
+	// Key algorithm change on request
+	rotated, err = (&Cert{KeyAlgorithm: KeyAlgorithmRSA2048}).Rotate(rotated, RotationOptions{Force: true, Overlap: time.Hour})
+	if assert.NoError(t, err) && assert.NotNil(t, rotated) {
+		assert.Equal(t, KeyAlgorithmRSA2048, keyAlgorithmOf(mustParseCerts(t, rotated.Cert)[0].PublicKey))
+	}
+}
@@ -17,21 +17,65 @@ package certs
 import (
 	"crypto"
 	"crypto/x509"
+	"sync/atomic"
+	"time"
 )
 
 // PEMBundle stores webhook certificates and private key
 type PEMBundle struct {
-	CACert  []byte // CA certificate (PEM-encoded)
-	Cert    []byte // Webhook certificate (signed by CA) (PEM-encoded)
-	PrivKey []byte // Webhook private key (PEM-encoded)
+	CACert    []byte // CA certificate (PEM-encoded). May contain several CA certificates during CA rotation, current one first.
+	Cert      []byte // Webhook certificate (signed by CA) (PEM-encoded)
+	PrivKey   []byte // Webhook private key (PEM-encoded)
+	CAPrivKey []byte // CA private key (PEM-encoded), to issue new webhook certificates
+}
+
+// BundleFiles names the PEM files a PEMBundle is written to
+type BundleFiles struct {
+	CACert    string // CA certificate file
+	Cert      string // Webhook certificate file
+	PrivKey   string // Webhook private key file
+	CAPrivKey string // CA private key file (not written if empty)
 }
 
 // Cert holds all useful data for certificate generation
 type Cert struct {
-	CN         string            // Common Name
-	Hosts      []string          // Host names to register in webhook certificate
-	Lifetime   int               // Certificate lifetime (years)
-	caCert     []byte            // CA certificate (PEM-encoded)
-	caTemplate *x509.Certificate // CA template (used to generate webhook certificate)
-	caPrivKey  crypto.Signer     // CA private key
+	CN                string            // Common Name
+	Hosts             []string          // Host names to register in webhook certificate
+	CALifetime        time.Duration     // CA certificate lifetime
+	LeafLifetime      time.Duration     // Webhook certificate lifetime (CA certificate lifetime if not set)
+	KeyAlgorithm      string            // Algorithm of generated private keys (see KeyAlgorithm* constants, ECDSA P-256 if not set)
+	SigningCACert     []byte            // Provided CA certificate (PEM-encoded) to sign webhook certificate with, instead of generating a self-signed CA
+	SigningCAKey      []byte            // Provided CA private key (PEM-encoded)
+	StoreSigningCAKey bool              // Keep provided CA private key in generated bundle, to issue new webhook certificates on rotation
+	caCert            []byte            // CA certificate (PEM-encoded)
+	caTemplate        *x509.Certificate // CA template (used to generate webhook certificate)
+	caPrivKey         crypto.Signer     // CA private key
+}
+
+// RotationOptions tells when and how to rotate webhook certificates
+type RotationOptions struct {
+	Force         bool          // always issue a new webhook certificate
+	RotateCA      bool          // roll the CA (new CA issuing a new webhook certificate)
+	RenewFraction float64       // rotate certificates once this fraction of their lifetime has elapsed (0 to disable)
+	Overlap       time.Duration // period during which previous CA is kept in CA bundle after a CA rollover
+}
+
+// KeyPairReloader serves webhook certificate and private key loaded from files, allowing to reload them
+type KeyPairReloader struct {
+	certFile string       // PEM-encoded webhook certificate
+	keyFile  string       // PEM-encoded webhook private key
+	keyPair  atomic.Value // *tls.Certificate currently served
+}
+
+// CertInfo describes a certificate
+type CertInfo struct {
+	Subject      string    `json:"subject"`
+	SANs         []string  `json:"sans,omitempty"` // DNS names and IP addresses
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	ExpiresIn    string    `json:"expiresIn"` // negative if expired
+	KeyAlgorithm string    `json:"keyAlgorithm"`
+	IsCA         bool      `json:"isCA"`
 }
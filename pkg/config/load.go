@@ -16,7 +16,11 @@ package config
 
 import (
 	"crypto/sha256"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"strings"
+	"talend/vault-sidecar-injector/pkg/policy"
 
 	"github.com/ghodss/yaml"
 	"k8s.io/klog"
@@ -27,31 +31,35 @@ func Load(whSvrParams WhSvrParameters) (*VSIConfig, error) {
 	klog.Infof("annotationKeyPrefix=%s", whSvrParams.AnnotationKeyPrefix)
 	klog.Infof("appLabelKey=%s", whSvrParams.AppLabelKey)
 	klog.Infof("appServiceLabelKey=%s", whSvrParams.AppServiceLabelKey)
+	klog.Infof("skipNamespaces=%s", whSvrParams.SkipNamespaces)
+
+	// Compute hash of whole config along the way
+	cfgHash := sha256.New()
 
 	// Load injection config
 	var injectionConfig InjectionConfig
-	err := loadYaml(whSvrParams.InjectionCfgFile, &injectionConfig)
+	err := loadYaml(whSvrParams.InjectionCfgFile, &injectionConfig, cfgHash)
 	if err != nil {
 		klog.Errorf("Failed to load injection configuration: %v", err)
 		return nil, err
 	}
 
 	// Load Vault proxy config
-	proxyConfig, err := loadString(whSvrParams.ProxyCfgFile)
+	proxyConfig, err := loadString(whSvrParams.ProxyCfgFile, cfgHash)
 	if err != nil {
 		klog.Errorf("Failed to load proxy configuration: %v", err)
 		return nil, err
 	}
 
 	// Load template
-	templateBlock, err := loadString(whSvrParams.TemplateBlockFile)
+	templateBlock, err := loadString(whSvrParams.TemplateBlockFile, cfgHash)
 	if err != nil {
 		klog.Errorf("Failed to load template: %v", err)
 		return nil, err
 	}
 
 	// Load default template
-	templateDefaultTmpl, err := loadString(whSvrParams.TemplateDefaultFile)
+	templateDefaultTmpl, err := loadString(whSvrParams.TemplateDefaultFile, cfgHash)
 	if err != nil {
 		klog.Errorf("Failed to load default template: %v", err)
 		return nil, err
@@ -59,26 +67,51 @@ func Load(whSvrParams WhSvrParameters) (*VSIConfig, error) {
 
 	// Load lifecycle hooks to inject into requesting pods
 	var hooks LifecycleHooks
-	err = loadYaml(whSvrParams.PodLifecycleHooksFile, &hooks)
+	err = loadYaml(whSvrParams.PodLifecycleHooksFile, &hooks, cfgHash)
 	if err != nil {
 		klog.Errorf("Failed to load pod's lifecycle hooks configuration: %v", err)
 		return nil, err
 	}
 
+	// Check namespaces to skip
+	skipNamespaces := splitList(whSvrParams.SkipNamespaces)
+	if err = validateSkipNamespaces(skipNamespaces); err != nil {
+		klog.Errorf("Invalid namespaces to skip: %v", err)
+		return nil, err
+	}
+
+	// Load optional admission policy
+	var admissionPolicy *policy.Policy
+	if whSvrParams.PolicyFile != "" {
+		admissionPolicy = &policy.Policy{}
+		if err = loadYaml(whSvrParams.PolicyFile, admissionPolicy, cfgHash); err != nil {
+			klog.Errorf("Failed to load admission policy: %v", err)
+			return nil, err
+		}
+
+		if err = admissionPolicy.Compile(); err != nil {
+			klog.Errorf("Invalid admission policy: %v", err)
+			return nil, err
+		}
+	}
+
 	return &VSIConfig{
 		VaultInjectorAnnotationKeyPrefix: whSvrParams.AnnotationKeyPrefix,
 		ApplicationLabelKey:              whSvrParams.AppLabelKey,
 		ApplicationServiceLabelKey:       whSvrParams.AppServiceLabelKey,
+		SkipNamespaces:                   skipNamespaces,
 		InjectionConfig:                  &injectionConfig,
 		ProxyConfig:                      proxyConfig,
 		TemplateBlock:                    templateBlock,
 		TemplateDefaultTmpl:              templateDefaultTmpl,
 		PodslifecycleHooks:               &hooks,
+		Policy:                           admissionPolicy,
+		Hash:                             fmt.Sprintf("%x", cfgHash.Sum(nil)),
 	}, nil
 }
 
-func loadString(fileName string) (string, error) {
-	data, err := loadRaw(fileName)
+func loadString(fileName string, h io.Writer) (string, error) {
+	data, err := loadRaw(fileName, h)
 	if err != nil {
 		return "", err
 	}
@@ -86,8 +119,8 @@ func loadString(fileName string) (string, error) {
 	return string(data), nil
 }
 
-func loadYaml(fileName string, obj interface{}) error {
-	data, err := loadRaw(fileName)
+func loadYaml(fileName string, obj interface{}, h io.Writer) error {
+	data, err := loadRaw(fileName, h)
 	if err != nil {
 		return err
 	}
@@ -95,12 +128,27 @@ func loadYaml(fileName string, obj interface{}) error {
 	return yaml.Unmarshal(data, obj)
 }
 
-func loadRaw(fileName string) ([]byte, error) {
+func loadRaw(fileName string, h io.Writer) ([]byte, error) {
 	data, err := ioutil.ReadFile(fileName)
 	if err != nil {
 		return nil, err
 	}
 	klog.Infof("Loading %s [sha256sum: %x]", fileName, sha256.Sum256(data))
 
+	h.Write(data)
+
 	return data, nil
 }
+
+// Split comma-separated list, ignoring empty values
+func splitList(list string) []string {
+	var values []string
+
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -16,6 +16,7 @@ package config
 
 import (
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/ghodss/yaml"
@@ -96,6 +97,65 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadPolicy(t *testing.T) {
+	whSvrParams := WhSvrParameters{
+		InjectionCfgFile:      "../../test/config/injectionconfig.yaml",
+		ProxyCfgFile:          "../../test/config/proxyconfig.hcl",
+		TemplateBlockFile:     "../../test/config/tmplblock.hcl",
+		TemplateDefaultFile:   "../../test/config/tmpldefault.tmpl",
+		PodLifecycleHooksFile: "../../test/config/podlifecyclehooks.yaml",
+	}
+
+	// No policy file: no policy
+	vsiCfg, err := Load(whSvrParams)
+	if assert.NoError(t, err) {
+		assert.Nil(t, vsiCfg.Policy)
+	}
+
+	whSvrParams.PolicyFile = "../../test/config/policy.yaml"
+	vsiCfg, err = Load(whSvrParams)
+	if assert.NoError(t, err) && assert.NotNil(t, vsiCfg.Policy) {
+		assert.Len(t, vsiCfg.Policy.Rules, 2)
+		assert.Equal(t, "team-a-secrets", vsiCfg.Policy.Rules[0].Name)
+		assert.True(t, vsiCfg.Policy.AllowUnknownPaths)
+	}
+
+	// Invalid rules are rejected
+	invalidPolicy, err := ioutil.TempFile("", "policy")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(invalidPolicy.Name())
+
+	invalidPolicy.WriteString("rules:\n  - name: invalid\n    expression: 'role'\n")
+	invalidPolicy.Close()
+
+	whSvrParams.PolicyFile = invalidPolicy.Name()
+	_, err = Load(whSvrParams)
+	assert.Error(t, err)
+}
+
+func TestLoadSkipNamespaces(t *testing.T) {
+	whSvrParams := WhSvrParameters{
+		InjectionCfgFile:      "../../test/config/injectionconfig.yaml",
+		ProxyCfgFile:          "../../test/config/proxyconfig.hcl",
+		TemplateBlockFile:     "../../test/config/tmplblock.hcl",
+		TemplateDefaultFile:   "../../test/config/tmpldefault.tmpl",
+		PodLifecycleHooksFile: "../../test/config/podlifecyclehooks.yaml",
+		SkipNamespaces:        "monitoring, team-*",
+	}
+
+	vsiCfg, err := Load(whSvrParams)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"monitoring", "team-*"}, vsiCfg.SkipNamespaces)
+	}
+
+	// Malformed patterns are rejected
+	whSvrParams.SkipNamespaces = "monitoring,team-["
+	_, err = Load(whSvrParams)
+	assert.Error(t, err)
+}
+
 func stringFromYamlFile(t *testing.T, filename string) string {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -15,36 +15,102 @@
 package config
 
 import (
+	"talend/vault-sidecar-injector/pkg/policy"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
 // CertParameters : Cert parameters
 type CertParameters struct {
-	CertOperation  string // operation on webhook certificates
-	CertSecretName string // name of generated or provided Kubernetes secret storing webhook certificates and private key
-	CertHostnames  string // host names to register in webhook certificate (comma-separated list)
-	CertLifetime   int    // lifetime in years for generated certificates
-	CACertFile     string // default filename for webhook CA certificate (PEM-encoded) in generated or provided k8s secret
-	CertFile       string // default filename for webhook certificate (PEM-encoded) in generated or provided k8s secret
-	KeyFile        string // default filename for webhook private key (PEM-encoded) in generated or provided k8s secret
+	CertOperation       string        // operation on webhook certificates
+	CertSecretName      string        // name of generated or provided Kubernetes secret storing webhook certificates and private key
+	CertHostnames       string        // host names to register in webhook certificate (comma-separated list)
+	CertLifetime        int           // lifetime in years for generated certificates (if CALifetime is not set)
+	CALifetime          time.Duration // lifetime of generated CA certificate
+	LeafLifetime        time.Duration // lifetime of generated webhook certificate (CA certificate lifetime if not set)
+	KeyAlgorithm        string        // algorithm of generated private keys
+	CACertFile          string        // default filename for webhook CA certificate (PEM-encoded) in generated or provided k8s secret
+	CertFile            string        // default filename for webhook certificate (PEM-encoded) in generated or provided k8s secret
+	KeyFile             string        // default filename for webhook private key (PEM-encoded) in generated or provided k8s secret
+	CAKeyFile           string        // default filename for webhook CA private key (PEM-encoded) in generated k8s secret
+	SigningCACertFile   string        // provided CA certificate (PEM-encoded) to sign webhook certificate with, instead of generating a CA
+	SigningCAKeyFile    string        // provided CA private key (PEM-encoded)
+	SigningCASecretName string        // name of k8s secret storing provided CA certificate and private key ('tls.crt' and 'tls.key' entries)
+	StoreSigningCAKey   bool          // store provided CA private key with webhook certificate, to issue new webhook certificates on rotation
+	RotateCA            bool          // on rotation, roll the CA instead of only issuing new webhook certificate
+	CAOverlap           time.Duration // on rotation, period during which previous CA is kept in CA bundle
+	WebhookCfgName      string        // name of MutatingWebhookConfiguration resource to update with new CA bundle on rotation (optional)
+	OutputDir           string        // directory to write generated certificates and private keys to, instead of creating k8s secret
+	OutputCAKey         bool          // also write CA private key to OutputDir (needed to rotate webhook certificate)
+	PrintCABundle       bool          // print base64-encoded CA certificate to set as webhook configurations' 'caBundle'
 }
 
 // WhSvrParameters : Webhook Server parameters
 type WhSvrParameters struct {
-	Port                  int    // webhook server port
-	MetricsPort           int    // metrics server port (Prometheus)
-	CACertFile            string // PEM-encoded webhook CA certificate
-	CertFile              string // PEM-encoded webhook certificate used for TLS
-	KeyFile               string // PEM-encoded webhook private key used for TLS
-	WebhookCfgName        string // name of MutatingWebhookConfiguration resource
-	AnnotationKeyPrefix   string // annotations key prefix
-	AppLabelKey           string // key for application label
-	AppServiceLabelKey    string // key for application's service label
-	InjectionCfgFile      string // path to injection configuration file
-	ProxyCfgFile          string // path to Vault proxy configuration file
-	TemplateBlockFile     string // path to template file
-	TemplateDefaultFile   string // path to default template content file
-	PodLifecycleHooksFile string // path to pod's lifecycle hooks file
+	Port                  int           // webhook server port
+	MetricsPort           int           // metrics server port (Prometheus)
+	CACertFile            string        // PEM-encoded webhook CA certificate
+	CertFile              string        // PEM-encoded webhook certificate used for TLS
+	KeyFile               string        // PEM-encoded webhook private key used for TLS
+	CertExpiryThreshold   time.Duration // readiness fails when webhook certificate expires within this duration
+	CertWatchInterval     time.Duration // polling interval to detect changes in webhook certificate files (0 to disable)
+	CertSecretName        string        // name of Kubernetes secret storing webhook certificates and private key
+	CAKeyFile             string        // filename for webhook CA private key (PEM-encoded) in k8s secret
+	CALifetime            time.Duration // lifetime of CA certificate issued on rotation (current one if not set)
+	LeafLifetime          time.Duration // lifetime of webhook certificate issued on rotation (current one if not set)
+	KeyAlgorithm          string        // algorithm of private keys generated on rotation (current one if not set)
+	CertRotation          bool          // rotate webhook certificates in background (leader-elected)
+	CertRotationFraction  float64       // rotate certificates once this fraction of their lifetime has elapsed
+	CertRotationOverlap   time.Duration // period during which previous CA is kept in CA bundle after a CA rollover
+	CertRotationInterval  time.Duration // period to check whether webhook certificates have to be rotated
+	ReadTimeout           time.Duration // max duration for reading the entire request, including the body (0 for no timeout)
+	WriteTimeout          time.Duration // max duration before timing out writes of the response (0 for no timeout)
+	IdleTimeout           time.Duration // max amount of time to wait for the next request on keep-alive connections (0 for no timeout)
+	MaxRequestBodySize    int64         // max size in bytes of admission requests' body (0 for no limit)
+	MaxConcurrentRequests int           // max number of admission requests processed at the same time (0 for no limit)
+	ShutdownDelay         time.Duration // on shutdown, time to wait once replica is marked unready before draining webhook server
+	ShutdownTimeout       time.Duration // on shutdown, max duration to drain in-flight admission requests (0 for no timeout)
+	AuditLog              string        // file to write audit records to ("-" for stdout, empty to disable)
+	OTLPEndpoint          string        // OTLP/HTTP endpoint URL to export traces to (OTEL_EXPORTER_OTLP_ENDPOINT env var used if empty)
+	WebhookCfgName        string        // name of MutatingWebhookConfiguration resource
+	CABundleReconcile     bool          // continuously reconcile webhook configurations' 'caBundle' with CA certificate (leader-elected)
+	Register              bool          // create or update MutatingWebhookConfiguration on startup
+	Dev                   bool          // local development mode (localhost, in-memory certificates, no Kubernetes cluster)
+	LeaseNamespace        string        // namespace of the Lease used for leader election
+	AnnotationKeyPrefix   string        // annotations key prefix
+	AppLabelKey           string        // key for application label
+	AppServiceLabelKey    string        // key for application's service label
+	SkipNamespaces        string        // comma-separated list of namespaces (or glob patterns) where injection is never performed
+	InjectionCfgFile      string        // path to injection configuration file
+	ProxyCfgFile          string        // path to Vault proxy configuration file
+	TemplateBlockFile     string        // path to template file
+	TemplateDefaultFile   string        // path to default template content file
+	PodLifecycleHooksFile string        // path to pod's lifecycle hooks file
+	PolicyFile            string        // path to admission policy file (optional)
+	ConfigWatchInterval   time.Duration // polling interval to detect changes in configuration files (0 to disable)
+}
+
+// RenderParameters : Render parameters
+type RenderParameters struct {
+	ManifestFile string // file containing the manifest to render ('-' for stdin)
+}
+
+// RegisterParameters : MutatingWebhookConfiguration registration parameters
+type RegisterParameters struct {
+	WebhookCfgName     string        // name of MutatingWebhookConfiguration resource
+	CertSecretName     string        // name of Kubernetes secret storing webhook CA certificate (CACertFile is read if empty)
+	CACertFile         string        // PEM-encoded webhook CA certificate (or name of secret entry)
+	ServiceName        string        // name of webhook's service
+	ServiceNamespace   string        // namespace of webhook's service (current namespace if empty)
+	ServicePort        int           // port of webhook's service
+	Path               string        // URL path of mutation endpoint
+	Operations         string        // comma-separated list of operations on pods sent to the webhook
+	NamespaceSelector  string        // label selector on namespaces of pods sent to the webhook
+	ObjectSelector     string        // label selector on pods sent to the webhook
+	FailurePolicy      string        // how errors and timeouts of the webhook are handled (Ignore or Fail)
+	Timeout            time.Duration // timeout of calls to the webhook
+	ReinvocationPolicy string        // whether webhook is called again if other admission plugins modify the pod (Never or IfNeeded)
 }
 
 // InjectionConfig : resources that will be injected (read from config file)
@@ -56,7 +122,7 @@ type InjectionConfig struct {
 
 // LifecycleHooks : lifecycle hooks to inject in requesting pod
 type LifecycleHooks struct {
-	PostStart *corev1.Handler `yaml:"postStart" json:"postStart"`
+	PostStart *corev1.LifecycleHandler `yaml:"postStart" json:"postStart"`
 }
 
 // VSIConfig : Vault Sidecar Injector configuration
@@ -65,11 +131,14 @@ type VSIConfig struct {
 	VaultInjectorAnnotationsFQ       map[string]string // supported annotations (fully-qualified with prefix if any)
 	ApplicationLabelKey              string            // key for application label
 	ApplicationServiceLabelKey       string            // key for application's service label
+	SkipNamespaces                   []string          // namespaces (or glob patterns) where injection is never performed
 	InjectionConfig                  *InjectionConfig  // injection configuration
 	ProxyConfig                      string            // Vault proxy configuration
 	TemplateBlock                    string            // template
 	TemplateDefaultTmpl              string            // default template content
 	PodslifecycleHooks               *LifecycleHooks   // pod's lifecycle hooks
+	Policy                           *policy.Policy    // admission policy (nil if none)
+	Hash                             string            // sha256 hash of loaded configuration files
 }
 
 type CertOperationType string
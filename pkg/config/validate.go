@@ -0,0 +1,67 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Validate : check that Vault Sidecar Injector's config is usable to inject pods
+func (vsiCfg *VSIConfig) Validate() error {
+	if vsiCfg.InjectionConfig == nil {
+		return errors.New("Injection configuration not loaded")
+	}
+
+	if len(vsiCfg.InjectionConfig.Containers) == 0 && len(vsiCfg.InjectionConfig.InitContainers) == 0 {
+		return errors.New("Injection configuration does not define any container to inject")
+	}
+
+	for _, cnt := range append(vsiCfg.InjectionConfig.InitContainers, vsiCfg.InjectionConfig.Containers...) {
+		if cnt.Name == "" || cnt.Image == "" {
+			return fmt.Errorf("Injection configuration defines a container with no name or image: '%s'", cnt.Name)
+		}
+	}
+
+	if vsiCfg.PodslifecycleHooks == nil {
+		return errors.New("Pod's lifecycle hooks configuration not loaded")
+	}
+
+	if vsiCfg.TemplateBlock == "" {
+		return errors.New("Template block is empty")
+	}
+
+	if vsiCfg.TemplateDefaultTmpl == "" {
+		return errors.New("Default template is empty")
+	}
+
+	if len(vsiCfg.VaultInjectorAnnotationsFQ) == 0 {
+		return errors.New("Supported annotations not computed")
+	}
+
+	return validateSkipNamespaces(vsiCfg.SkipNamespaces)
+}
+
+// validateSkipNamespaces : check that namespaces to skip are valid glob patterns (a malformed one would silently match nothing)
+func validateSkipNamespaces(skipNamespaces []string) error {
+	for _, pattern := range skipNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid namespace pattern '%s' in namespaces to skip: %v", pattern, err)
+		}
+	}
+
+	return nil
+}
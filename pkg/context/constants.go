@@ -24,10 +24,16 @@ const (
 	VaultInjectorAnnotationRoleKey       = "role"        // Optional. To explicitly provide Vault role to use
 	VaultInjectorAnnotationSATokenKey    = "sa-token"    // Optional. Full path to service account token used for Vault Kubernetes authentication
 	VaultInjectorAnnotationWorkloadKey   = "workload"    // Optional and deprecated. If set to "job", supplementary container and signaling mechanism will also be injected to properly handle k8s job
+	VaultInjectorAnnotationSkipKey       = "skip"        // Optional. Explicitly opt pod out of injection (also supported as a label), even if requested by namespace defaults
 	// Output annotation (set by VSI webhook)
 	VaultInjectorAnnotationStatusKey = "status" // Not to be set by requesting pods: set by the Webhook Admission Controller if injection ok
 )
 
+const (
+	//--- Prefix for namespace annotations providing default values for pods' annotations (e.g. "default-role" for "role")
+	VaultInjectorNamespaceDefaultPrefix = "default-"
+)
+
 const (
 	//--- Vault Sidecar Injector status
 	VaultInjectorStatusInjected = "injected"
@@ -38,10 +44,38 @@ const (
 	VaultAppRoleAuthMethod = "approle"    // Vault AppRole auth method
 )
 
+const (
+	//--- Reasons for skipped injections
+	SkipReasonIgnoredNamespace = "IgnoredNamespace"
+	SkipReasonOptOut           = "OptOut"
+	SkipReasonNotRequested     = "NotRequested"
+)
+
+const (
+	//--- Reasons for denied injections
+	ReasonInvalidObject              = "InvalidObject"
+	ReasonNoContainer                = "NoContainer"
+	ReasonTooManyContainers          = "TooManyContainers"
+	ReasonMissingLabel               = "MissingLabel"
+	ReasonMissingServiceAccountToken = "MissingServiceAccountToken"
+	ReasonMissingCommand             = "MissingCommand"
+	ReasonUnsupportedAuthMethod      = "UnsupportedAuthMethod"
+	ReasonUnsupportedSecretsType     = "UnsupportedSecretsType"
+	ReasonUnsupportedInjectionMethod = "UnsupportedInjectionMethod"
+	ReasonUnsupportedCombination     = "UnsupportedCombination"
+	ReasonUnsupportedLifecycleHook   = "UnsupportedLifecycleHook"
+	ReasonMismatchedSecretsCount     = "MismatchedSecretsCount"
+	ReasonPolicyViolation            = "PolicyViolation"
+	ReasonTimeout                    = "Timeout"     // admission request timeout expired
+	ReasonNotInjected                = "NotInjected" // validation only: injection requested but not performed
+	ReasonUnknown                    = "Unknown"
+)
+
 const (
 	//--- JSON Patch operations
 	JsonPatchOpAdd     = "add"
 	JsonPatchOpReplace = "replace"
+	JsonPatchOpRemove  = "remove"
 )
 
 const (
@@ -0,0 +1,48 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/klog"
+)
+
+// NewInjectionError : create an error with the reason why injection is denied
+func NewInjectionError(reason, format string, a ...interface{}) error {
+	return &InjectionError{Reason: reason, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *InjectionError) Error() string {
+	return e.Message
+}
+
+// AddWarning : log a warning and keep it to be returned to the client
+func (context *InjectionContext) AddWarning(format string, a ...interface{}) {
+	warning := fmt.Sprintf(format, a...)
+	klog.Warning(warning)
+	context.Warnings = append(context.Warnings, warning)
+}
+
+// GetErrorReason : return the reason carried by an injection error or 'Unknown' for any other error
+func GetErrorReason(err error) string {
+	var injectionErr *InjectionError
+	if errors.As(err, &injectionErr) {
+		return injectionErr.Reason
+	}
+
+	return ReasonUnknown
+}
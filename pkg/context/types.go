@@ -23,6 +23,8 @@ type InjectionContext struct {
 	VaultRole                      string
 	ModesStatus                    map[string]bool
 	ModesConfig                    map[string]ModeConfig
+	InjectedContainers             []string // names of injected init containers and containers
+	Warnings                       []string // warnings returned to the client along with the admission response
 }
 
 // ModeConfig : interface for mode's config
@@ -30,6 +32,12 @@ type ModeConfig interface {
 	GetTemplate() string
 }
 
+// InjectionError : error returned when submitted pod cannot be injected, along with the reason
+type InjectionError struct {
+	Reason  string // short, CamelCase reason (see constants)
+	Message string
+}
+
 // PatchOperation : this struct represents a JSON Patch operation (see http://jsonpatch.com/)
 type PatchOperation struct {
 	Op    string      `json:"op"`
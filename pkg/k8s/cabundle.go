@@ -0,0 +1,194 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// NewCABundleReconciler returns a reconciler keeping webhook configurations' 'caBundle' in sync with provided CA certificate file
+func (k8sctl *K8SClient) NewCABundleReconciler(caCertFile string) *CABundleReconciler {
+	return &CABundleReconciler{
+		k8sctl:       k8sctl,
+		caCertFile:   caCertFile,
+		resyncPeriod: 10 * time.Minute,
+		retryPeriod:  5 * time.Second,
+		trigger:      make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests a reconciliation (e.g. on CA certificate change). Reconciliation only happens on the leader.
+func (reconciler *CABundleReconciler) Trigger() {
+	if reconciler == nil {
+		return
+	}
+
+	select {
+	case reconciler.trigger <- struct{}{}:
+	default: // reconciliation already pending
+	}
+}
+
+// Run watches webhook configurations and reconciles their 'caBundle' on change until stop channel is closed
+func (reconciler *CABundleReconciler) Run(stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(reconciler.k8sctl.Interface, reconciler.resyncPeriod,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", reconciler.k8sctl.WebhookCfgName).String()
+		}))
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { reconciler.Trigger() },
+		UpdateFunc: func(oldObj, newObj interface{}) { reconciler.Trigger() },
+	}
+
+	mutatingInformer := factory.Admissionregistration().V1().MutatingWebhookConfigurations().Informer()
+	mutatingInformer.AddEventHandler(handler)
+	validatingInformer := factory.Admissionregistration().V1().ValidatingWebhookConfigurations().Informer()
+	validatingInformer.AddEventHandler(handler)
+
+	factory.Start(stopCh)
+	defer factory.Shutdown()
+
+	if !cache.WaitForCacheSync(stopCh, mutatingInformer.HasSynced, validatingInformer.HasSynced) {
+		klog.Error("Failed to sync webhook configurations cache")
+		return
+	}
+
+	reconciler.Trigger()
+
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-reconciler.trigger:
+		case <-retry:
+		}
+
+		retry = nil
+		if err := reconciler.Reconcile(); err != nil {
+			klog.Errorf("Failed to reconcile caBundle, retry in %v: %v", reconciler.retryPeriod, err)
+			retry = time.After(reconciler.retryPeriod)
+		}
+	}
+}
+
+// Reconcile sets 'caBundle' of every webhook entry to the CA certificate, if not already the case
+func (reconciler *CABundleReconciler) Reconcile() error {
+	caPEM, err := reconciler.caBundle()
+	if err != nil {
+		return err
+	}
+
+	return reconciler.k8sctl.UpdateCABundle(caPEM)
+}
+
+// caBundle returns CA certificate to set in webhook configurations
+func (reconciler *CABundleReconciler) caBundle() ([]byte, error) {
+	return reconciler.k8sctl.CABundle(reconciler.caCertFile)
+}
+
+// CABundle returns CA certificate from webhook secret if known (most up-to-date source, e.g. on certificate rotation), from provided file otherwise
+func (k8sctl *K8SClient) CABundle(caCertFile string) ([]byte, error) {
+	var caPEM []byte
+
+	if k8sctl.WebhookSecretName != "" {
+		secret, err := k8sctl.GetCertSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		caPEM = secret.Data[k8sctl.WebhookCACertName]
+	} else {
+		var err error
+		if caPEM, err = ioutil.ReadFile(caCertFile); err != nil {
+			klog.Errorf("Failed to read CA cert file: %s", err)
+			return nil, err
+		}
+	}
+
+	if len(caPEM) == 0 {
+		return nil, errors.New("Empty CA certificate")
+	}
+
+	return caPEM, nil
+}
+
+// UpdateCABundle sets 'caBundle' of every webhook entry of the MutatingWebhookConfiguration (and ValidatingWebhookConfiguration, if any), if not already the case
+func (k8sctl *K8SClient) UpdateCABundle(caPEM []byte) error {
+	webhookCfgName := k8sctl.WebhookCfgName
+	admissionClient := k8sctl.AdmissionregistrationV1()
+
+	mutatingCfg, err := admissionClient.MutatingWebhookConfigurations().Get(context.TODO(), webhookCfgName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Error getting MutatingWebhookConfiguration %s: %s", webhookCfgName, err)
+		return err
+	}
+
+	updated := false
+	for idx := range mutatingCfg.Webhooks {
+		if !bytes.Equal(mutatingCfg.Webhooks[idx].ClientConfig.CABundle, caPEM) {
+			mutatingCfg.Webhooks[idx].ClientConfig.CABundle = caPEM
+			updated = true
+		}
+	}
+
+	if updated {
+		klog.Infof("Updating caBundle of MutatingWebhookConfiguration %s", webhookCfgName)
+		// Update is rejected if resource changed since we got it (reconciliation happens again on update event)
+		if _, err = admissionClient.MutatingWebhookConfigurations().Update(context.TODO(), mutatingCfg, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Error updating MutatingWebhookConfiguration's caBundle: %s", err)
+			return err
+		}
+	}
+
+	// Optional ValidatingWebhookConfiguration, with same name
+	validatingCfg, err := admissionClient.ValidatingWebhookConfigurations().Get(context.TODO(), webhookCfgName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		klog.Errorf("Error getting ValidatingWebhookConfiguration %s: %s", webhookCfgName, err)
+		return err
+	}
+
+	updated = false
+	for idx := range validatingCfg.Webhooks {
+		if !bytes.Equal(validatingCfg.Webhooks[idx].ClientConfig.CABundle, caPEM) {
+			validatingCfg.Webhooks[idx].ClientConfig.CABundle = caPEM
+			updated = true
+		}
+	}
+
+	if updated {
+		klog.Infof("Updating caBundle of ValidatingWebhookConfiguration %s", webhookCfgName)
+		if _, err = admissionClient.ValidatingWebhookConfigurations().Update(context.TODO(), validatingCfg, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Error updating ValidatingWebhookConfiguration's caBundle: %s", err)
+			return err
+		}
+	}
+
+	return nil
+}
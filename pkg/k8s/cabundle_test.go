@@ -0,0 +1,188 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testWebhookCfgName = "vault-sidecar-injector"
+	testCAPEM          = "-----BEGIN CERTIFICATE-----\ntest\n-----END CERTIFICATE-----\n"
+)
+
+func newTestReconciler(t *testing.T, objects ...runtime.Object) (*CABundleReconciler, *fake.Clientset) {
+	caCertFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caCertFile, []byte(testCAPEM), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	clientset := fake.NewSimpleClientset(objects...)
+	k8sctl := &K8SClient{Interface: clientset, WebhookData: &WebhookData{WebhookCfgName: testWebhookCfgName}}
+
+	reconciler := k8sctl.NewCABundleReconciler(caCertFile)
+	reconciler.retryPeriod = 10 * time.Millisecond
+
+	return reconciler, clientset
+}
+
+func mutatingWebhookCfg(caBundles ...string) *admregv1.MutatingWebhookConfiguration {
+	cfg := &admregv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: testWebhookCfgName}}
+	for _, caBundle := range caBundles {
+		cfg.Webhooks = append(cfg.Webhooks, admregv1.MutatingWebhook{
+			Name:         "vault-sidecar-injector.talend.org",
+			ClientConfig: admregv1.WebhookClientConfig{CABundle: []byte(caBundle)},
+		})
+	}
+	return cfg
+}
+
+func validatingWebhookCfg(caBundles ...string) *admregv1.ValidatingWebhookConfiguration {
+	cfg := &admregv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: testWebhookCfgName}}
+	for _, caBundle := range caBundles {
+		cfg.Webhooks = append(cfg.Webhooks, admregv1.ValidatingWebhook{
+			Name:         "vault-sidecar-injector.talend.org",
+			ClientConfig: admregv1.WebhookClientConfig{CABundle: []byte(caBundle)},
+		})
+	}
+	return cfg
+}
+
+func countUpdates(clientset *fake.Clientset) int {
+	updates := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "update" {
+			updates++
+		}
+	}
+	return updates
+}
+
+func TestReconcileCABundle(t *testing.T) {
+	reconciler, clientset := newTestReconciler(t, mutatingWebhookCfg("", "stale"), validatingWebhookCfg(""))
+
+	if err := reconciler.Reconcile(); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	mutatingCfg, _ := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	for _, webhook := range mutatingCfg.Webhooks {
+		assert.Equal(t, testCAPEM, string(webhook.ClientConfig.CABundle))
+	}
+
+	validatingCfg, _ := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	for _, webhook := range validatingCfg.Webhooks {
+		assert.Equal(t, testCAPEM, string(webhook.ClientConfig.CABundle))
+	}
+
+	assert.Equal(t, 2, countUpdates(clientset))
+
+	// Nothing to update once in sync
+	clientset.ClearActions()
+	if err := reconciler.Reconcile(); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	assert.Equal(t, 0, countUpdates(clientset))
+}
+
+func TestReconcileCABundleNoValidatingCfg(t *testing.T) {
+	reconciler, clientset := newTestReconciler(t, mutatingWebhookCfg(""))
+
+	if err := reconciler.Reconcile(); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	assert.Equal(t, 1, countUpdates(clientset))
+}
+
+func TestReconcileCABundleErrors(t *testing.T) {
+	// No MutatingWebhookConfiguration
+	reconciler, _ := newTestReconciler(t)
+	assert.Error(t, reconciler.Reconcile())
+
+	// Missing CA certificate
+	reconciler, _ = newTestReconciler(t, mutatingWebhookCfg(""))
+	reconciler.caCertFile = filepath.Join(t.TempDir(), "missing.crt")
+	assert.Error(t, reconciler.Reconcile())
+}
+
+func TestRunCABundleReconciler(t *testing.T) {
+	reconciler, clientset := newTestReconciler(t, mutatingWebhookCfg(""))
+
+	// First update fails: reconciliation must be retried
+	failed := false
+	clientset.PrependReactor("update", "mutatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !failed {
+			failed = true
+			return true, nil, assert.AnError
+		}
+		return false, nil, nil
+	})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go reconciler.Run(stopCh)
+
+	caBundle := func() string {
+		mutatingCfg, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+		if err != nil {
+			return ""
+		}
+		return string(mutatingCfg.Webhooks[0].ClientConfig.CABundle)
+	}
+
+	assert.Eventually(t, func() bool { return caBundle() == testCAPEM }, 5*time.Second, 10*time.Millisecond)
+
+	// caBundle reset by someone else: restored on update event
+	_, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(context.TODO(), mutatingWebhookCfg(""), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Eventually(t, func() bool { return caBundle() == testCAPEM }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRunCABundleReconcilerWithLeaderElection(t *testing.T) {
+	reconciler, clientset := newTestReconciler(t, mutatingWebhookCfg(""))
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	reconciler.k8sctl.RunWithLeaderElection(stopCh, "default", testWebhookCfgName+"-cabundle", "replica-1", reconciler.Run)
+
+	assert.Eventually(t, func() bool {
+		lease, err := clientset.CoordinationV1().Leases("default").Get(context.TODO(), testWebhookCfgName+"-cabundle", metav1.GetOptions{})
+		return err == nil && lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == "replica-1"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		mutatingCfg, _ := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+		return string(mutatingCfg.Webhooks[0].ClientConfig.CABundle) == testCAPEM
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTriggerNilSafe(t *testing.T) {
+	var reconciler *CABundleReconciler
+	reconciler.Trigger()
+}
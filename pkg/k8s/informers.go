@@ -0,0 +1,41 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"errors"
+
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// NewNamespaceLister starts a namespace informer and returns a lister reading from its cache, once synced
+func (k8sctl *K8SClient) NewNamespaceLister(stopCh <-chan struct{}) (corelisters.NamespaceLister, error) {
+	factory := informers.NewSharedInformerFactory(k8sctl.Interface, 0)
+	namespaceInformer := factory.Core().V1().Namespaces()
+	namespaceLister := namespaceInformer.Lister()
+
+	factory.Start(stopCh)
+
+	if !cache.WaitForCacheSync(stopCh, namespaceInformer.Informer().HasSynced) {
+		err := errors.New("Failed to sync namespace cache")
+		klog.Error(err.Error())
+		return nil, err
+	}
+
+	return namespaceLister, nil
+}
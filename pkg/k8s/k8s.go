@@ -15,6 +15,7 @@
 package k8s
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -23,33 +24,88 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 )
 
-// New : init K8S client
-func New(whk *WebhookData) *K8SClient {
-	// In-cluster K8S client: must exist obviously
-	k8sConfig, err := rest.InClusterConfig()
+// New : init K8S client, either from kubeconfig (if file or context provided, or if not running in a pod) or from in-cluster config
+func New(clientCfg *ClientConfig, whk *WebhookData) (*K8SClient, error) {
+	k8sConfig, namespace, err := clientCfg.load()
 	if err != nil {
-		klog.Errorf("Failed to load in-cluster K8S config: %s", err)
-		panic(err)
+		return nil, err
 	}
 
 	k8sClientset, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
 		klog.Errorf("Error creating K8S client: %s", err)
-		panic(err)
+		return nil, err
 	}
 
-	return &K8SClient{k8sClientset, whk}
+	return &K8SClient{Interface: k8sClientset, WebhookData: whk, namespace: namespace}, nil
 }
 
-// CreateCertSecret creates a Kubernetes Secret storing webhook CA, certificate and private key
-func (k8sctl *K8SClient) CreateCertSecret(ca, cert, key []byte) error {
+// Load K8S config along with namespace of kubeconfig's context (empty for in-cluster config)
+func (clientCfg *ClientConfig) load() (*rest.Config, string, error) {
+	if clientCfg == nil {
+		clientCfg = &ClientConfig{}
+	}
+
+	if clientCfg.Kubeconfig == "" && clientCfg.Context == "" {
+		k8sConfig, err := rest.InClusterConfig()
+		if err == nil {
+			return k8sConfig, "", nil
+		} else if err != rest.ErrNotInCluster {
+			klog.Errorf("Failed to load in-cluster K8S config: %s", err)
+			return nil, "", err
+		}
+	}
+
+	// Out-of-cluster: use provided kubeconfig file, or KUBECONFIG env var, or ~/.kube/config
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = clientCfg.Kubeconfig
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: clientCfg.Context})
+
+	k8sConfig, err := kubeconfig.ClientConfig()
+	if err != nil {
+		klog.Errorf("Failed to load K8S config from kubeconfig: %s", err)
+		return nil, "", err
+	}
+
+	namespace, _, err := kubeconfig.Namespace()
+	if err != nil {
+		klog.Errorf("Failed to get namespace from kubeconfig: %s", err)
+		return nil, "", err
+	}
+
+	return k8sConfig, namespace, nil
+}
+
+// CurrentNamespace returns namespace of the webhook: from 'POD_NAMESPACE' env var if set, from kubeconfig's context for out-of-cluster client, from service account otherwise
+func (k8sctl *K8SClient) CurrentNamespace() (string, error) {
+	if ns, ok := os.LookupEnv("POD_NAMESPACE"); ok && strings.TrimSpace(ns) != "" {
+		return strings.TrimSpace(ns), nil
+	}
+
+	if k8sctl.namespace != "" {
+		return k8sctl.namespace, nil
+	}
+
+	ns, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		klog.Errorf("Failed to get current namespace from 'POD_NAMESPACE' env var or service account: %s", err)
+		return "", errors.New("Failed to get current namespace")
+	}
+
+	return strings.TrimSpace(string(ns)), nil
+}
+
+// CreateCertSecret creates a Kubernetes Secret storing webhook CA, certificate and private key (and CA private key, if provided)
+func (k8sctl *K8SClient) CreateCertSecret(ca, cert, key, caKey []byte) error {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: k8sctl.WebhookSecretName,
@@ -61,24 +117,24 @@ func (k8sctl *K8SClient) CreateCertSecret(ca, cert, key []byte) error {
 		},
 	}
 
-	// Get current namespace
-	ns, ok := os.LookupEnv("POD_NAMESPACE")
-	if !ok {
-		klog.Errorf("Failed to get current namespace from 'POD_NAMESPACE' env var")
-		return errors.New("Failed to get current namespace")
+	if len(caKey) > 0 {
+		secret.Data[k8sctl.WebhookCAKeyName] = caKey
 	}
 
-	// Other way to get current namespace:
-	//ns, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	// Get current namespace
+	ns, err := k8sctl.CurrentNamespace()
+	if err != nil {
+		return err
+	}
 
 	// If secret already exists: log a warning before deleting it
-	if _, err := k8sctl.CoreV1().Secrets(strings.TrimSpace(string(ns))).Get(k8sctl.WebhookSecretName, metav1.GetOptions{}); err == nil {
+	if _, err := k8sctl.CoreV1().Secrets(ns).Get(context.TODO(), k8sctl.WebhookSecretName, metav1.GetOptions{}); err == nil {
 		klog.Warning("Webhook secret already exists: will be deleted then created again from new generated certificate")
 		k8sctl.DeleteCertSecret()
 	}
 
 	// Create Secret in same namespace as webhook
-	_, err := k8sctl.CoreV1().Secrets(strings.TrimSpace(string(ns))).Create(secret)
+	_, err = k8sctl.CoreV1().Secrets(ns).Create(context.TODO(), secret, metav1.CreateOptions{})
 	if err != nil {
 		klog.Errorf("Failed creating Webhook secret: %s", err)
 		return err
@@ -90,17 +146,13 @@ func (k8sctl *K8SClient) CreateCertSecret(ca, cert, key []byte) error {
 // DeleteCertSecret deletes the Kubernetes Secret used for storing webhook CA, certificate and private key
 func (k8sctl *K8SClient) DeleteCertSecret() error {
 	// Get current namespace
-	ns, ok := os.LookupEnv("POD_NAMESPACE")
-	if !ok {
-		klog.Errorf("Failed to get current namespace from 'POD_NAMESPACE' env var")
-		return errors.New("Failed to get current namespace")
+	ns, err := k8sctl.CurrentNamespace()
+	if err != nil {
+		return err
 	}
 
-	// Other way to get current namespace:
-	//ns, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
-
 	// Delete Secret
-	err := k8sctl.CoreV1().Secrets(strings.TrimSpace(string(ns))).Delete(k8sctl.WebhookSecretName, &metav1.DeleteOptions{})
+	err = k8sctl.CoreV1().Secrets(ns).Delete(context.TODO(), k8sctl.WebhookSecretName, metav1.DeleteOptions{})
 	if err != nil {
 		klog.Errorf("Failed deleting Webhook secret: %s", err)
 		return err
@@ -109,7 +161,29 @@ func (k8sctl *K8SClient) DeleteCertSecret() error {
 	return nil
 }
 
-// PatchWebhookConfiguration reads CA certificate then patches MutatingWebhookConfiguration's caBundle
+// GetCertSecret returns webhook CA, certificate and private key (and CA private key, if any) from Kubernetes Secret
+func (k8sctl *K8SClient) GetCertSecret() (*corev1.Secret, error) {
+	return k8sctl.GetSecret(k8sctl.WebhookSecretName)
+}
+
+// GetSecret returns Kubernetes Secret from webhook's namespace
+func (k8sctl *K8SClient) GetSecret(name string) (*corev1.Secret, error) {
+	// Get current namespace
+	ns, err := k8sctl.CurrentNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := k8sctl.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed getting secret %s: %s", name, err)
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// PatchWebhookConfiguration reads CA certificate then patches MutatingWebhookConfiguration's caBundle (and ValidatingWebhookConfiguration's one, if any)
 func (k8sctl *K8SClient) PatchWebhookConfiguration(cacertfile string) error {
 	caPEM, err := ioutil.ReadFile(cacertfile)
 	if err != nil {
@@ -125,21 +199,33 @@ func (k8sctl *K8SClient) PatchWebhookConfiguration(cacertfile string) error {
 		}]`, base64.StdEncoding.EncodeToString(caPEM)))
 
 	// Patch MutatingWebhookConfiguration resource with CA (should be base64-encoded PEM-encoded)
-	if _, err = k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(k8sctl.WebhookCfgName, metav1.GetOptions{}); err == nil {
+	if _, err = k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), k8sctl.WebhookCfgName, metav1.GetOptions{}); err == nil {
 		// v1 support
 		klog.Infof("Patching MutatingWebhookConfiguration v1 resource %v", k8sctl.WebhookCfgName)
-		if _, err = k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations().Patch(k8sctl.WebhookCfgName, types.JSONPatchType, webhookPatch); err != nil {
+		if _, err = k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations().Patch(context.TODO(), k8sctl.WebhookCfgName, types.JSONPatchType, webhookPatch, metav1.PatchOptions{}); err != nil {
 			klog.Errorf("Error patching MutatingWebhookConfiguration's caBundle: %s", err)
 			return err
 		}
 	} else {
 		// v1beta1 support
 		klog.Infof("Patching MutatingWebhookConfiguration v1beta1 resource %v", k8sctl.WebhookCfgName)
-		if _, err = k8sctl.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Patch(k8sctl.WebhookCfgName, types.JSONPatchType, webhookPatch); err != nil {
+		if _, err = k8sctl.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Patch(context.TODO(), k8sctl.WebhookCfgName, types.JSONPatchType, webhookPatch, metav1.PatchOptions{}); err != nil {
 			klog.Errorf("Error patching MutatingWebhookConfiguration's caBundle: %s", err)
 			return err
 		}
 	}
 
+	// Optional ValidatingWebhookConfiguration, with same name (v1 only)
+	if _, err = k8sctl.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), k8sctl.WebhookCfgName, metav1.GetOptions{}); err == nil {
+		klog.Infof("Patching ValidatingWebhookConfiguration v1 resource %v", k8sctl.WebhookCfgName)
+		if _, err = k8sctl.AdmissionregistrationV1().ValidatingWebhookConfigurations().Patch(context.TODO(), k8sctl.WebhookCfgName, types.JSONPatchType, webhookPatch, metav1.PatchOptions{}); err != nil {
+			klog.Errorf("Error patching ValidatingWebhookConfiguration's caBundle: %s", err)
+			return err
+		}
+	} else if !apierrors.IsNotFound(err) {
+		klog.Errorf("Error getting ValidatingWebhookConfiguration: %s", err)
+		return err
+	}
+
 	return nil
 }
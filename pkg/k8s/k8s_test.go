@@ -0,0 +1,107 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+- name: staging
+  cluster:
+    server: https://staging.example.com:6443
+users:
+- name: admin
+  user:
+    token: test
+contexts:
+- name: kind
+  context:
+    cluster: kind
+    user: admin
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+    namespace: vault-sidecar-injector
+current-context: kind
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return kubeconfig
+}
+
+func TestNewFromKubeconfig(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "")
+	kubeconfig := writeTestKubeconfig(t)
+
+	// Current context, without namespace
+	k8sctl, err := New(&ClientConfig{Kubeconfig: kubeconfig}, &WebhookData{})
+	if assert.NoError(t, err) {
+		ns, err := k8sctl.CurrentNamespace()
+		assert.NoError(t, err)
+		assert.Equal(t, "default", ns)
+	}
+
+	// Provided context, with namespace
+	k8sctl, err = New(&ClientConfig{Kubeconfig: kubeconfig, Context: "staging"}, &WebhookData{})
+	if assert.NoError(t, err) {
+		ns, err := k8sctl.CurrentNamespace()
+		assert.NoError(t, err)
+		assert.Equal(t, "vault-sidecar-injector", ns)
+
+		// Env var takes precedence
+		t.Setenv("POD_NAMESPACE", "other")
+		ns, err = k8sctl.CurrentNamespace()
+		assert.NoError(t, err)
+		assert.Equal(t, "other", ns)
+	}
+
+	// KUBECONFIG env var used if no kubeconfig file is provided
+	t.Setenv("KUBECONFIG", kubeconfig)
+	_, err = New(&ClientConfig{Context: "staging"}, &WebhookData{})
+	assert.NoError(t, err)
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("HOME", t.TempDir())
+
+	// No in-cluster config and no kubeconfig
+	_, err := New(nil, &WebhookData{})
+	assert.Error(t, err)
+
+	// Unknown kubeconfig file or context
+	_, err = New(&ClientConfig{Kubeconfig: filepath.Join(t.TempDir(), "missing")}, &WebhookData{})
+	assert.Error(t, err)
+
+	_, err = New(&ClientConfig{Kubeconfig: writeTestKubeconfig(t), Context: "unknown"}, &WebhookData{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+)
+
+// RunWithLeaderElection calls provided function in new routine, only on the replica holding the Lease, until stop channel is closed.
+// Function's stop channel is closed when leadership is lost.
+func (k8sctl *K8SClient) RunWithLeaderElection(stopCh <-chan struct{}, leaseNamespace, leaseName, identity string, run func(stopCh <-chan struct{})) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	go func() {
+		for ctx.Err() == nil { // Campaign again if leadership is lost
+			leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+				Lock: &resourcelock.LeaseLock{
+					LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+					Client:     k8sctl.CoordinationV1(),
+					LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+				},
+				LeaseDuration:   15 * time.Second,
+				RenewDeadline:   10 * time.Second,
+				RetryPeriod:     2 * time.Second,
+				ReleaseOnCancel: true,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: func(leaderCtx context.Context) {
+						klog.Infof("Leading lease %s/%s (identity: %s)", leaseNamespace, leaseName, identity)
+						run(leaderCtx.Done())
+					},
+					OnStoppedLeading: func() {
+						klog.Infof("Stopped leading lease %s/%s (identity: %s)", leaseNamespace, leaseName, identity)
+					},
+				},
+			})
+		}
+	}()
+}
@@ -0,0 +1,140 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// RegisterWebhook creates MutatingWebhookConfiguration, or updates it if it already exists, with a single webhook entry pointing to the webhook's service
+func (k8sctl *K8SClient) RegisterWebhook(reg *WebhookRegistration) error {
+	if k8sctl.WebhookCfgName == "" {
+		return errors.New("Missing MutatingWebhookConfiguration name")
+	}
+
+	webhook, err := k8sctl.mutatingWebhook(reg)
+	if err != nil {
+		return err
+	}
+
+	mutatingCfgs := k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations()
+
+	// Several replicas may register at the same time: retry on conflict
+	for attempt := 1; ; attempt++ {
+		mutatingCfg, err := mutatingCfgs.Get(context.TODO(), k8sctl.WebhookCfgName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			klog.Infof("Creating MutatingWebhookConfiguration %s", k8sctl.WebhookCfgName)
+			_, err = mutatingCfgs.Create(context.TODO(), &admregv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: k8sctl.WebhookCfgName,
+				},
+				Webhooks: []admregv1.MutatingWebhook{*webhook},
+			}, metav1.CreateOptions{})
+		} else if err == nil {
+			klog.Infof("Updating MutatingWebhookConfiguration %s", k8sctl.WebhookCfgName)
+			mutatingCfg.Webhooks = []admregv1.MutatingWebhook{*webhook}
+			_, err = mutatingCfgs.Update(context.TODO(), mutatingCfg, metav1.UpdateOptions{})
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= maxRegisterAttempts || !(apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err)) {
+			klog.Errorf("Error registering MutatingWebhookConfiguration %s: %s", k8sctl.WebhookCfgName, err)
+			return err
+		}
+	}
+}
+
+// UnregisterWebhook deletes MutatingWebhookConfiguration, if it exists
+func (k8sctl *K8SClient) UnregisterWebhook() error {
+	if k8sctl.WebhookCfgName == "" {
+		return errors.New("Missing MutatingWebhookConfiguration name")
+	}
+
+	klog.Infof("Deleting MutatingWebhookConfiguration %s", k8sctl.WebhookCfgName)
+	err := k8sctl.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(context.TODO(), k8sctl.WebhookCfgName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		klog.Warningf("MutatingWebhookConfiguration %s does not exist", k8sctl.WebhookCfgName)
+		return nil
+	} else if err != nil {
+		klog.Errorf("Error deleting MutatingWebhookConfiguration %s: %s", k8sctl.WebhookCfgName, err)
+		return err
+	}
+
+	return nil
+}
+
+func (k8sctl *K8SClient) mutatingWebhook(reg *WebhookRegistration) (*admregv1.MutatingWebhook, error) {
+	if reg.ServiceName == "" {
+		return nil, errors.New("Missing webhook's service name")
+	}
+
+	if len(reg.CABundle) == 0 {
+		return nil, errors.New("Empty CA certificate")
+	}
+
+	serviceNamespace := reg.ServiceNamespace
+	if serviceNamespace == "" {
+		var err error
+		if serviceNamespace, err = k8sctl.CurrentNamespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	path := reg.Path
+	port := reg.ServicePort
+	timeoutSeconds := reg.TimeoutSeconds
+	failurePolicy := reg.FailurePolicy
+	reinvocationPolicy := reg.ReinvocationPolicy
+	sideEffects := admregv1.SideEffectClassNoneOnDryRun // Events are emitted on denied injections, except for dry run requests
+
+	return &admregv1.MutatingWebhook{
+		Name: webhookName,
+		ClientConfig: admregv1.WebhookClientConfig{
+			Service: &admregv1.ServiceReference{
+				Name:      reg.ServiceName,
+				Namespace: serviceNamespace,
+				Path:      &path,
+				Port:      &port,
+			},
+			CABundle: reg.CABundle,
+		},
+		Rules: []admregv1.RuleWithOperations{
+			{
+				Operations: reg.Operations,
+				Rule: admregv1.Rule{
+					APIGroups:   []string{""},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"pods"},
+				},
+			},
+		},
+		NamespaceSelector:       reg.NamespaceSelector,
+		ObjectSelector:          reg.ObjectSelector,
+		FailurePolicy:           &failurePolicy,
+		TimeoutSeconds:          &timeoutSeconds,
+		ReinvocationPolicy:      &reinvocationPolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1", "v1beta1"},
+	}, nil
+}
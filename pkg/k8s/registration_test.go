@@ -0,0 +1,147 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestRegistration() *WebhookRegistration {
+	return &WebhookRegistration{
+		ServiceName:        "vault-sidecar-injector",
+		ServicePort:        443,
+		Path:               "/mutate",
+		Operations:         []admregv1.OperationType{admregv1.Create},
+		NamespaceSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"vault-injection": "enabled"}},
+		FailurePolicy:      admregv1.Ignore,
+		TimeoutSeconds:     10,
+		ReinvocationPolicy: admregv1.IfNeededReinvocationPolicy,
+		CABundle:           []byte(testCAPEM),
+	}
+}
+
+func getTestMutatingWebhookCfg(t *testing.T, clientset *fake.Clientset) *admregv1.MutatingWebhookConfiguration {
+	mutatingCfg, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get MutatingWebhookConfiguration: %v", err)
+	}
+
+	return mutatingCfg
+}
+
+func TestRegisterWebhook(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	clientset := fake.NewSimpleClientset()
+	k8sctl := newTestK8SClient(clientset)
+
+	// Creation
+	assert.NoError(t, k8sctl.RegisterWebhook(newTestRegistration()))
+
+	mutatingCfg := getTestMutatingWebhookCfg(t, clientset)
+	if assert.Len(t, mutatingCfg.Webhooks, 1) {
+		webhook := mutatingCfg.Webhooks[0]
+		assert.Equal(t, "vault-sidecar-injector.talend.org", webhook.Name)
+		assert.Equal(t, "vault-sidecar-injector", webhook.ClientConfig.Service.Name)
+		assert.Equal(t, testNamespace, webhook.ClientConfig.Service.Namespace)
+		assert.Equal(t, "/mutate", *webhook.ClientConfig.Service.Path)
+		assert.Equal(t, int32(443), *webhook.ClientConfig.Service.Port)
+		assert.Equal(t, testCAPEM, string(webhook.ClientConfig.CABundle))
+		assert.Equal(t, []admregv1.OperationType{admregv1.Create}, webhook.Rules[0].Operations)
+		assert.Equal(t, []string{"pods"}, webhook.Rules[0].Resources)
+		assert.Equal(t, "enabled", webhook.NamespaceSelector.MatchLabels["vault-injection"])
+		assert.Nil(t, webhook.ObjectSelector)
+		assert.Equal(t, admregv1.Ignore, *webhook.FailurePolicy)
+		assert.Equal(t, int32(10), *webhook.TimeoutSeconds)
+		assert.Equal(t, admregv1.IfNeededReinvocationPolicy, *webhook.ReinvocationPolicy)
+		assert.Equal(t, admregv1.SideEffectClassNoneOnDryRun, *webhook.SideEffects)
+	}
+
+	// Update of existing configuration: webhook entries are replaced
+	reg := newTestRegistration()
+	reg.ServiceNamespace = "other"
+	reg.FailurePolicy = admregv1.Fail
+	reg.ObjectSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"vault-injection": "true"}}
+	assert.NoError(t, k8sctl.RegisterWebhook(reg))
+
+	mutatingCfg = getTestMutatingWebhookCfg(t, clientset)
+	if assert.Len(t, mutatingCfg.Webhooks, 1) {
+		webhook := mutatingCfg.Webhooks[0]
+		assert.Equal(t, "other", webhook.ClientConfig.Service.Namespace)
+		assert.Equal(t, admregv1.Fail, *webhook.FailurePolicy)
+		assert.Equal(t, "true", webhook.ObjectSelector.MatchLabels["vault-injection"])
+	}
+}
+
+func TestRegisterWebhookRetry(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	clientset := fake.NewSimpleClientset()
+	k8sctl := newTestK8SClient(clientset)
+
+	// Another replica creates the configuration first
+	failures := 0
+	clientset.PrependReactor("create", "mutatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures == 0 {
+			failures++
+			return true, nil, apierrors.NewAlreadyExists(admregv1.Resource("mutatingwebhookconfigurations"), testWebhookCfgName)
+		}
+		return false, nil, nil
+	})
+
+	assert.NoError(t, k8sctl.RegisterWebhook(newTestRegistration()))
+	assert.Equal(t, 1, failures)
+	assert.Len(t, getTestMutatingWebhookCfg(t, clientset).Webhooks, 1)
+}
+
+func TestRegisterWebhookErrors(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	k8sctl := newTestK8SClient(fake.NewSimpleClientset())
+
+	reg := newTestRegistration()
+	reg.ServiceName = ""
+	assert.Error(t, k8sctl.RegisterWebhook(reg))
+
+	reg = newTestRegistration()
+	reg.CABundle = nil
+	assert.Error(t, k8sctl.RegisterWebhook(reg))
+
+	k8sctl.WebhookCfgName = ""
+	assert.Error(t, k8sctl.RegisterWebhook(newTestRegistration()))
+	assert.Error(t, k8sctl.UnregisterWebhook())
+}
+
+func TestUnregisterWebhook(t *testing.T) {
+	clientset := fake.NewSimpleClientset(mutatingWebhookCfg(testCAPEM))
+	k8sctl := newTestK8SClient(clientset)
+
+	assert.NoError(t, k8sctl.UnregisterWebhook())
+
+	_, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Nothing to delete
+	assert.NoError(t, k8sctl.UnregisterWebhook())
+}
@@ -0,0 +1,102 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"talend/vault-sidecar-injector/pkg/certs"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// RotateCertSecret rotates webhook certificates stored in webhook secret then updates webhook configurations' 'caBundle' (if webhook configuration name is known).
+// Returns false if there was nothing to rotate.
+func (k8sctl *K8SClient) RotateCertSecret(cert *certs.Cert, opts certs.RotationOptions) (bool, error) {
+	secret, err := k8sctl.GetCertSecret()
+	if err != nil {
+		return false, err
+	}
+
+	if secret.Data == nil || len(secret.Data[k8sctl.WebhookCACertName]) == 0 || len(secret.Data[k8sctl.WebhookCertName]) == 0 {
+		err = fmt.Errorf("Webhook secret %s/%s does not contain CA certificate '%s' and certificate '%s'", secret.Namespace, secret.Name, k8sctl.WebhookCACertName, k8sctl.WebhookCertName)
+		klog.Error(err.Error())
+		return false, err
+	}
+
+	bundle, err := cert.Rotate(&certs.PEMBundle{
+		CACert:    secret.Data[k8sctl.WebhookCACertName],
+		Cert:      secret.Data[k8sctl.WebhookCertName],
+		PrivKey:   secret.Data[k8sctl.WebhookKeyName],
+		CAPrivKey: secret.Data[k8sctl.WebhookCAKeyName],
+	}, opts)
+	if err != nil || bundle == nil {
+		return false, err
+	}
+
+	secret.Data[k8sctl.WebhookCACertName] = bundle.CACert
+	secret.Data[k8sctl.WebhookCertName] = bundle.Cert
+	secret.Data[k8sctl.WebhookKeyName] = bundle.PrivKey
+	secret.Data[k8sctl.WebhookCAKeyName] = bundle.CAPrivKey
+
+	// Update is rejected if secret changed since we got it
+	if _, err = k8sctl.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("Failed updating Webhook secret: %s", err)
+		return false, err
+	}
+
+	klog.Infof("Rotated webhook certificates stored in secret %s/%s", secret.Namespace, secret.Name)
+
+	// Do not wait for mounted secret to be refreshed: new CA bundle is still trusting previous CA, if any
+	if k8sctl.WebhookCfgName != "" {
+		if err = k8sctl.UpdateCABundle(bundle.CACert); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// NewCertRotator returns a rotator checking webhook certificates every period
+func (k8sctl *K8SClient) NewCertRotator(cert *certs.Cert, opts certs.RotationOptions, checkPeriod time.Duration) *CertRotator {
+	return &CertRotator{
+		k8sctl:      k8sctl,
+		cert:        cert,
+		opts:        opts,
+		checkPeriod: checkPeriod,
+	}
+}
+
+// Run rotates webhook certificates when due until stop channel is closed
+func (rotator *CertRotator) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(rotator.checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		// Work on a copy: names may be taken from current certificate
+		cert := *rotator.cert
+		if _, err := rotator.k8sctl.RotateCertSecret(&cert, rotator.opts); err != nil {
+			klog.Errorf("Failed to rotate webhook certificates, retry in %v: %v", rotator.checkPeriod, err)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"talend/vault-sidecar-injector/pkg/certs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace  = "vault-sidecar-injector"
+	testSecretName = "vault-sidecar-injector-cert"
+)
+
+func newTestCertSecret(t *testing.T) (*corev1.Secret, *certs.PEMBundle) {
+	bundle, err := (&certs.Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 365 * 24 * time.Hour, LeafLifetime: 7 * 24 * time.Hour}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace},
+		Data: map[string][]byte{
+			"ca.crt":  bundle.CACert,
+			"tls.crt": bundle.Cert,
+			"tls.key": bundle.PrivKey,
+			"ca.key":  bundle.CAPrivKey,
+		},
+	}, bundle
+}
+
+func newTestK8SClient(clientset *fake.Clientset) *K8SClient {
+	return &K8SClient{
+		Interface: clientset,
+		WebhookData: &WebhookData{
+			WebhookSecretName: testSecretName,
+			WebhookCACertName: "ca.crt",
+			WebhookCertName:   "tls.crt",
+			WebhookKeyName:    "tls.key",
+			WebhookCAKeyName:  "ca.key",
+			WebhookCfgName:    testWebhookCfgName,
+		},
+	}
+}
+
+func getTestCertSecret(t *testing.T, clientset *fake.Clientset) *corev1.Secret {
+	secret, err := clientset.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+
+	return secret
+}
+
+func TestRotateCertSecret(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	secret, bundle := newTestCertSecret(t)
+	clientset := fake.NewSimpleClientset(secret, mutatingWebhookCfg("", ""))
+	k8sctl := newTestK8SClient(clientset)
+
+	// Nothing due
+	rotated, err := k8sctl.RotateCertSecret(&certs.Cert{LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{RenewFraction: 0.5})
+	assert.NoError(t, err)
+	assert.False(t, rotated)
+
+	// New webhook certificate, webhook configuration's caBundle updated
+	rotated, err = k8sctl.RotateCertSecret(&certs.Cert{LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{Force: true})
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+
+	secret = getTestCertSecret(t, clientset)
+	assert.Equal(t, bundle.CACert, secret.Data["ca.crt"])
+	assert.NotEqual(t, bundle.Cert, secret.Data["tls.crt"])
+
+	mutatingCfg, _ := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	for _, webhook := range mutatingCfg.Webhooks {
+		assert.Equal(t, bundle.CACert, webhook.ClientConfig.CABundle)
+	}
+
+	// CA rollover: both CAs in caBundle
+	rotated, err = k8sctl.RotateCertSecret(&certs.Cert{CALifetime: 365 * 24 * time.Hour, LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{RotateCA: true, Overlap: time.Hour})
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+
+	secret = getTestCertSecret(t, clientset)
+	assert.NotEqual(t, bundle.CAPrivKey, secret.Data["ca.key"])
+	assert.True(t, bytes.HasSuffix(secret.Data["ca.crt"], bundle.CACert))
+
+	mutatingCfg, _ = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	for _, webhook := range mutatingCfg.Webhooks {
+		assert.Equal(t, secret.Data["ca.crt"], webhook.ClientConfig.CABundle)
+	}
+}
+
+func TestRotateCertSecretWithoutData(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace}}
+	k8sctl := newTestK8SClient(fake.NewSimpleClientset(secret))
+
+	rotated, err := k8sctl.RotateCertSecret(&certs.Cert{LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{Force: true})
+	assert.Error(t, err)
+	assert.False(t, rotated)
+
+	// Certificate missing
+	secret, _ = newTestCertSecret(t)
+	delete(secret.Data, "tls.crt")
+	k8sctl = newTestK8SClient(fake.NewSimpleClientset(secret))
+
+	rotated, err = k8sctl.RotateCertSecret(&certs.Cert{LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{Force: true})
+	assert.Error(t, err)
+	assert.False(t, rotated)
+}
+
+func TestRunCertRotator(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	secret, bundle := newTestCertSecret(t)
+	clientset := fake.NewSimpleClientset(secret, mutatingWebhookCfg(""))
+	k8sctl := newTestK8SClient(clientset)
+
+	// Tiny fraction: webhook certificate due at each check
+	rotator := k8sctl.NewCertRotator(&certs.Cert{LeafLifetime: 7 * 24 * time.Hour}, certs.RotationOptions{RenewFraction: 0.0001}, time.Hour)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go rotator.Run(stopCh)
+
+	assert.Eventually(t, func() bool {
+		return !bytes.Equal(bundle.Cert, getTestCertSecret(t, clientset).Data["tls.crt"])
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReconcileCABundleFromSecret(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", testNamespace)
+
+	secret, bundle := newTestCertSecret(t)
+	clientset := fake.NewSimpleClientset(secret, mutatingWebhookCfg(""))
+
+	// CA certificate file is not used when secret is known
+	reconciler := newTestK8SClient(clientset).NewCABundleReconciler("missing.crt")
+	if err := reconciler.Reconcile(); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	mutatingCfg, _ := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), testWebhookCfgName, metav1.GetOptions{})
+	assert.Equal(t, bundle.CACert, mutatingCfg.Webhooks[0].ClientConfig.CABundle)
+}
@@ -15,12 +15,24 @@
 package k8s
 
 import (
+	"talend/vault-sidecar-injector/pkg/certs"
+	"time"
+
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s "k8s.io/client-go/kubernetes"
 )
 
 type K8SClient struct {
-	*k8s.Clientset // Inner Kubernetes client
-	*WebhookData   // Webhook data
+	k8s.Interface        // Inner Kubernetes client
+	*WebhookData         // Webhook data
+	namespace     string // Namespace of kubeconfig's context (out-of-cluster client only)
+}
+
+// ClientConfig selects the Kubernetes cluster to connect to
+type ClientConfig struct {
+	Kubeconfig string // Path to kubeconfig file (in-cluster config, then default kubeconfig loading rules, if empty)
+	Context    string // Context of kubeconfig to use (current context if empty)
 }
 
 type WebhookData struct {
@@ -28,5 +40,38 @@ type WebhookData struct {
 	WebhookCACertName string // Name of secret entry for webhook CA certificate
 	WebhookCertName   string // Name of secret entry for webhook certificate
 	WebhookKeyName    string // Name of secret entry for webhook private key
-	WebhookCfgName    string // Name of MutatingWebhookConfiguration resource
+	WebhookCAKeyName  string // Name of secret entry for webhook CA private key (to issue new webhook certificates)
+	WebhookCfgName    string // Name of MutatingWebhookConfiguration resource (and of optional ValidatingWebhookConfiguration resource)
+}
+
+// CABundleReconciler keeps 'caBundle' of every webhook entry of the MutatingWebhookConfiguration (and ValidatingWebhookConfiguration, if any) equal to the CA certificate
+type CABundleReconciler struct {
+	k8sctl       *K8SClient
+	caCertFile   string        // PEM-encoded webhook CA certificate
+	resyncPeriod time.Duration // period to reconcile even without any change
+	retryPeriod  time.Duration // period to retry on reconciliation error
+	trigger      chan struct{} // request reconciliation
+}
+
+// CertRotator periodically rotates webhook certificates stored in webhook secret
+type CertRotator struct {
+	k8sctl      *K8SClient
+	cert        *certs.Cert           // Certificate data used to issue new certificates
+	opts        certs.RotationOptions // When and how to rotate certificates
+	checkPeriod time.Duration         // Period to check whether certificates have to be rotated
+}
+
+// WebhookRegistration describes the webhook entry of the MutatingWebhookConfiguration to create or update
+type WebhookRegistration struct {
+	ServiceName        string                          // Name of the Service in front of the webhook
+	ServiceNamespace   string                          // Namespace of the Service (current namespace if empty)
+	ServicePort        int32                           // Port of the Service
+	Path               string                          // URL path of the mutation endpoint
+	Operations         []admregv1.OperationType        // Operations on pods sent to the webhook
+	NamespaceSelector  *metav1.LabelSelector           // Only pods in matching namespaces are sent to the webhook (all if nil)
+	ObjectSelector     *metav1.LabelSelector           // Only matching pods are sent to the webhook (all if nil)
+	FailurePolicy      admregv1.FailurePolicyType      // How errors and timeouts of the webhook are handled
+	TimeoutSeconds     int32                           // Timeout of calls to the webhook
+	ReinvocationPolicy admregv1.ReinvocationPolicyType // Whether webhook is called again if other admission plugins modify the pod
+	CABundle           []byte                          // PEM-encoded CA certificate to trust webhook certificate
 }
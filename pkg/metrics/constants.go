@@ -0,0 +1,48 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+const (
+	//--- Metrics namespace (prefix)
+	metricsNamespace = "vsi"
+)
+
+const (
+	//--- Admission results
+	ResultInjected = "injected" // pod mutated
+	ResultSkipped  = "skipped"  // no mutation required
+	ResultDenied   = "denied"   // pod rejected
+)
+
+const (
+	//--- Validation results
+	ResultValid    = "valid"    // pod not requesting injection or properly injected
+	ResultRejected = "rejected" // pod requesting injection but not (or badly) injected
+)
+
+const (
+	//--- Reasons for rejected HTTP requests (before or while processing admission request)
+	RejectionBodyTooLarge    = "BodyTooLarge"    // request body exceeds max size
+	RejectionTooManyRequests = "TooManyRequests" // max number of concurrent admission requests reached
+	RejectionTimeout         = "Timeout"         // admission request timeout expired
+)
+
+const (
+	//--- Instrumented handlers
+	HandlerServe         = "serve"          // HTTP handler: decoding, mutation and encoding of AdmissionReview
+	HandlerMutate        = "mutate"         // mutation only
+	HandlerServeValidate = "serve_validate" // HTTP handler: decoding, validation and encoding of AdmissionReview
+	HandlerValidate      = "validate"       // validation only
+)
@@ -0,0 +1,180 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"sync/atomic"
+	m "talend/vault-sidecar-injector/pkg/mode"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	admissionRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_requests_total",
+			Help:      "Number of admission requests handled by the webhook, by namespace and result (injected, skipped, denied)",
+		},
+		[]string{"namespace", "result"},
+	)
+
+	admissionDenials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_denials_total",
+			Help:      "Number of denied admission requests, by namespace and reason",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	admissionSkips = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_skips_total",
+			Help:      "Number of admission requests not leading to any mutation, by namespace and reason",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	injections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "injections_total",
+			Help:      "Number of injected pods, by namespace, enabled modes, Vault auth method, secrets type and secrets injection method",
+		},
+		[]string{"namespace", "modes", "auth_method", "secrets_type", "secrets_injection_method"},
+	)
+
+	validationRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "validation_requests_total",
+			Help:      "Number of validation requests handled by the webhook, by namespace and result (valid, rejected)",
+		},
+		[]string{"namespace", "result"},
+	)
+
+	validationRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "validation_rejections_total",
+			Help:      "Number of pods rejected by validation, by namespace and reason",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	requestRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_rejections_total",
+			Help:      "Number of HTTP requests rejected by the webhook server, by reason (BodyTooLarge, TooManyRequests, Timeout)",
+		},
+		[]string{"reason"},
+	)
+
+	configInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_info",
+			Help:      "Configuration in use, identified by its sha256 hash (value is always 1)",
+		},
+		[]string{"sha256"},
+	)
+
+	certificateExpiry = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_certificate_expiry_seconds",
+			Help:      "Number of seconds until the webhook certificate currently served expires (negative once expired)",
+		},
+		func() float64 {
+			notAfter, ok := certificateNotAfter.Load().(time.Time)
+			if !ok {
+				return 0
+			}
+			return time.Until(notAfter).Seconds()
+		},
+	)
+
+	admissionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_duration_seconds",
+			Help:      "Latency of admission requests processing, by handler (serve, mutate, serve_validate, validate)",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14), // from 0.5ms to ~4s
+		},
+		[]string{"handler"},
+	)
+)
+
+// Expiration date of the webhook certificate currently served
+var certificateNotAfter atomic.Value
+
+func init() {
+	prometheus.MustRegister(admissionRequests, admissionDenials, admissionSkips, injections, validationRequests, validationRejections, requestRejections, configInfo, certificateExpiry, admissionDuration)
+}
+
+// Skipped : count admission request not leading to any mutation along with the reason
+func Skipped(namespace, reason string) {
+	admissionRequests.WithLabelValues(namespace, ResultSkipped).Inc()
+	admissionSkips.WithLabelValues(namespace, reason).Inc()
+}
+
+// Denied : count rejected admission request along with the reason
+func Denied(namespace, reason string) {
+	admissionRequests.WithLabelValues(namespace, ResultDenied).Inc()
+	admissionDenials.WithLabelValues(namespace, reason).Inc()
+}
+
+// Injected : count mutated pod along with injection settings
+func Injected(namespace string, modesStatus map[string]bool, authMethod, secretsType, secretsInjectionMethod string) {
+	admissionRequests.WithLabelValues(namespace, ResultInjected).Inc()
+	injections.WithLabelValues(namespace, strings.Join(m.GetEnabledModes(modesStatus), ","), authMethod, secretsType, secretsInjectionMethod).Inc()
+}
+
+// Validated : count pod passing validation
+func Validated(namespace string) {
+	validationRequests.WithLabelValues(namespace, ResultValid).Inc()
+}
+
+// Rejected : count pod rejected by validation along with the reason
+func Rejected(namespace, reason string) {
+	validationRequests.WithLabelValues(namespace, ResultRejected).Inc()
+	validationRejections.WithLabelValues(namespace, reason).Inc()
+}
+
+// RequestRejected : count HTTP request rejected by the webhook server along with the reason
+func RequestRejected(reason string) {
+	requestRejections.WithLabelValues(reason).Inc()
+}
+
+// ConfigLoaded : expose hash of the configuration in use
+func ConfigLoaded(hash string) {
+	configInfo.Reset()
+	configInfo.WithLabelValues(hash).Set(1)
+}
+
+// CertificateLoaded : expose time left before expiration of the webhook certificate now served
+func CertificateLoaded(notAfter time.Time) {
+	certificateNotAfter.Store(notAfter)
+}
+
+// ObserveDuration : record time spent in handler since provided start time (to use with 'defer')
+func ObserveDuration(handler string, start time.Time) {
+	admissionDuration.WithLabelValues(handler).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	Skipped("ns1", "NotRequested")
+	Denied("ns1", "MissingLabel")
+	Denied("ns1", "MissingLabel")
+	Injected("ns2", map[string]bool{"secrets": true, "proxy": true, "job": false}, "kubernetes", "dynamic", "file")
+	Validated("ns3")
+	Rejected("ns3", "NotInjected")
+	RequestRejected(RejectionTooManyRequests)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(admissionRequests.WithLabelValues("ns1", ResultSkipped)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(admissionSkips.WithLabelValues("ns1", "NotRequested")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(admissionRequests.WithLabelValues("ns1", ResultDenied)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(admissionDenials.WithLabelValues("ns1", "MissingLabel")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(admissionRequests.WithLabelValues("ns2", ResultInjected)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(injections.WithLabelValues("ns2", "proxy,secrets", "kubernetes", "dynamic", "file")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(validationRequests.WithLabelValues("ns3", ResultValid)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(validationRequests.WithLabelValues("ns3", ResultRejected)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(validationRejections.WithLabelValues("ns3", "NotInjected")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requestRejections.WithLabelValues(RejectionTooManyRequests)))
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	CertificateLoaded(time.Now().Add(time.Hour))
+	assert.InDelta(t, 3600, testutil.ToFloat64(certificateExpiry), 5)
+
+	CertificateLoaded(time.Now().Add(-time.Hour))
+	assert.InDelta(t, -3600, testutil.ToFloat64(certificateExpiry), 5)
+}
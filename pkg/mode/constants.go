@@ -19,4 +19,5 @@ const (
 	VaultInjectorModeSecrets = "secrets" // Enable fetching of secrets from Vault store
 	VaultInjectorModeProxy   = "proxy"   // Enable local Vault proxy
 	VaultInjectorModeJob     = "job"     // Enable handling of Kubernetes Job
+	VaultInjectorModePKI     = "pki"     // Enable issuance of TLS certificates from Vault PKI Secrets Engine
 )
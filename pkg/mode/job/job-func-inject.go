@@ -15,7 +15,6 @@
 package job
 
 import (
-	"errors"
 	ctx "talend/vault-sidecar-injector/pkg/context"
 	m "talend/vault-sidecar-injector/pkg/mode"
 	"talend/vault-sidecar-injector/pkg/mode/secrets"
@@ -26,7 +25,7 @@ import (
 
 func jobModeInject(containerBasePath string, podContainers []corev1.Container, containerName string, env []corev1.EnvVar, context *ctx.InjectionContext) (bool, error) {
 	if (containerBasePath == ctx.JsonPathContainers) && (len(podContainers) != 1) {
-		err := errors.New("Submitted pod should contain only one container")
+		err := ctx.NewInjectionError(ctx.ReasonTooManyContainers, "Submitted pod should contain only one container")
 		klog.Errorf("[%s] %s", m.VaultInjectorModeJob, err.Error())
 		return false, err
 	}
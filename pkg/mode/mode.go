@@ -15,7 +15,9 @@
 package mode
 
 import (
+	"fmt"
 	"os"
+	"sort"
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/klog"
@@ -33,8 +35,8 @@ func RegisterMode(modeInfo VaultInjectorModeInfo) {
 	}
 }
 
-// GetModesStatus : get modes' status
-func GetModesStatus(requestedModes []string, modes map[string]bool) {
+// GetModesStatus : get modes' status, returning warnings about ignored requested modes
+func GetModesStatus(requestedModes []string, modes map[string]bool) (warnings []string) {
 	var defaultModeKey string
 
 	// Init modes for current injection context
@@ -71,13 +73,32 @@ func GetModesStatus(requestedModes []string, modes map[string]bool) {
 				}
 
 				if !bModeFound {
-					klog.Warningf("Ignore unknown requested Vault Sidecar Injector mode: %s", requestedMode)
+					warning := fmt.Sprintf("Ignore unknown requested Vault Sidecar Injector mode: %s", requestedMode)
+					klog.Warning(warning)
+					warnings = append(warnings, warning)
 				}
 			}
 		}
 	} else { // If no mode(s) provided then only enable default mode
 		modes[defaultModeKey] = true
 	}
+
+	return
+}
+
+// GetEnabledModes : get sorted list of enabled modes
+func GetEnabledModes(modesStatus map[string]bool) []string {
+	var enabledModes []string
+
+	for mode, enabled := range modesStatus {
+		if enabled {
+			enabledModes = append(enabledModes, mode)
+		}
+	}
+
+	// Modes are stored in a map: sort them to get consistent results
+	sort.Strings(enabledModes)
+	return enabledModes
 }
 
 // Use assert.ElementsMatch for comparing slices, but with a bool result.
@@ -0,0 +1,58 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import "talend/vault-sidecar-injector/pkg/config"
+
+const (
+	//--- Vault Sidecar Injector modes annotation keys (without prefix)
+	vaultInjectorAnnotationPKIMountKey      = "pki-mount"       // Optional. Path of the PKI Secrets Engine, "pki" by default.
+	vaultInjectorAnnotationPKIRoleKey       = "pki-role"        // Optional. PKI role used to issue certificates. If not set, use application label.
+	vaultInjectorAnnotationPKICommonNameKey = "pki-common-name" // Optional. Certificate's common name. If not set, "<service label>.<namespace>.svc".
+	vaultInjectorAnnotationPKIAltNamesKey   = "pki-alt-names"   // Optional. Certificate's DNS alternative names. Several values separated by ','.
+	vaultInjectorAnnotationPKITTLKey        = "pki-ttl"         // Optional. Certificate's TTL. If not set, PKI role's TTL applies.
+	vaultInjectorAnnotationPKINotifyKey     = "pki-notify"      // Optional. Command to run after certificate files are rendered.
+)
+
+const (
+	pkiContainerName          = config.VaultAgentContainerName // Name of our pki container to inject
+	vaultDefaultPKIEnginePath = "pki"                          // Default path for Vault PKI Secrets Engine if no 'pki-mount' annotation
+	pkiAnnotationSeparator    = ","                            // Separator for pki annotations' values
+)
+
+const (
+	//--- Files rendered into the secrets volume, with matching fields of PKI Secrets Engine's response
+	pkiCertDestination = "cert.pem"
+	pkiKeyDestination  = "key.pem"
+	pkiCADestination   = "ca.pem"
+	pkiCertDataField   = "certificate"
+	pkiKeyDataField    = "private_key"
+	pkiCADataField     = "issuing_ca"
+)
+
+const (
+	//--- Vault Agent placeholders related to modes (shared with secrets mode's template block)
+	pkiVaultPathPlaceholder       = "<VSI_SECRETS_VAULT_SECRETS_PATH>"
+	pkiDestinationPlaceholder     = "<VSI_SECRETS_DESTINATION>"
+	pkiTemplateContentPlaceholder = "<VSI_SECRETS_TEMPLATE_CONTENT>"
+	pkiTemplateCommandPlaceholder = "<VSI_SECRETS_TEMPLATE_COMMAND_TO_RUN>"
+)
+
+const (
+	//--- Vault Agent env vars related to modes
+	pkiTemplatesPlaceholderEnv = "VSI_PKI_TEMPLATES_PLACEHOLDER"
+	pkiPodNameEnv              = "POD_NAME"      // Resolved at runtime to compute certificate's default names
+	pkiPodNamespaceEnv         = "POD_NAMESPACE" // Resolved at runtime to compute certificate's default names
+)
@@ -0,0 +1,107 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import (
+	"fmt"
+	"strings"
+	cfg "talend/vault-sidecar-injector/pkg/config"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	m "talend/vault-sidecar-injector/pkg/mode"
+
+	"k8s.io/klog"
+)
+
+func pkiModeCompute(config *cfg.VSIConfig, labels, annotations map[string]string) (ctx.ModeConfig, error) {
+	pkiMount := strings.Trim(annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKIMountKey]], "/")
+	pkiRole := annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKIRoleKey]]
+	commonName := annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKICommonNameKey]]
+	altNames := annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKIAltNamesKey]]
+	ttl := annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKITTLKey]]
+	notifyCmd := annotations[config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKINotifyKey]]
+
+	if pkiMount == "" { // Use default
+		pkiMount = vaultDefaultPKIEnginePath
+	}
+
+	if pkiRole == "" { // Use application label as default PKI role
+		pkiRole = labels[config.ApplicationLabelKey]
+
+		if pkiRole == "" {
+			err := ctx.NewInjectionError(ctx.ReasonMissingLabel, "Submitted pod must contain label %s or annotation %s", config.ApplicationLabelKey, config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKIRoleKey])
+			klog.Errorf("[%s] %s", m.VaultInjectorModePKI, err.Error())
+			return nil, err
+		}
+	}
+
+	// Default names are derived from service label, pod's name and namespace. As pod's name may not be known at admission time (generated
+	// names), they are resolved by Vault Agent at runtime from env vars of the injected container.
+	applicationServiceLabel := labels[config.ApplicationServiceLabelKey]
+
+	if (commonName == "" || altNames == "") && applicationServiceLabel == "" {
+		err := ctx.NewInjectionError(ctx.ReasonMissingLabel, "Submitted pod must contain label %s or annotations %s and %s", config.ApplicationServiceLabelKey,
+			config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKICommonNameKey], config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationPKIAltNamesKey])
+		klog.Errorf("[%s] %s", m.VaultInjectorModePKI, err.Error())
+		return nil, err
+	}
+
+	var issueArgs []string
+
+	if commonName == "" { // "<service>.<namespace>.svc"
+		issueArgs = append(issueArgs, fmt.Sprintf("(printf \"common_name=%%[1]s.%%[2]s.svc\" %q (env %q))", applicationServiceLabel, pkiPodNamespaceEnv))
+	} else {
+		issueArgs = append(issueArgs, fmt.Sprintf("%q", "common_name="+commonName))
+	}
+
+	if altNames == "" { // "<pod>,<service>,<service>.<namespace>,<service>.<namespace>.svc"
+		issueArgs = append(issueArgs, fmt.Sprintf("(printf \"alt_names=%%[1]s,%%[2]s,%%[2]s.%%[3]s,%%[2]s.%%[3]s.svc\" (env %q) %q (env %q))", pkiPodNameEnv, applicationServiceLabel, pkiPodNamespaceEnv))
+	} else {
+		names := strings.Split(altNames, pkiAnnotationSeparator)
+		for nameIdx := range names {
+			names[nameIdx] = strings.TrimSpace(names[nameIdx])
+		}
+
+		issueArgs = append(issueArgs, fmt.Sprintf("%q", "alt_names="+strings.Join(names, pkiAnnotationSeparator)))
+	}
+
+	if ttl != "" { // Otherwise PKI role's TTL applies
+		issueArgs = append(issueArgs, fmt.Sprintf("%q", "ttl="+ttl))
+	}
+
+	// Same path and arguments in all templates: Vault Agent issues one single certificate shared by the rendered files and renews it before expiry
+	issuePath := pkiMount + "/issue/" + pkiRole
+	secret := fmt.Sprintf("secret %q %s", issuePath, strings.Join(issueArgs, " "))
+
+	var templateBlock string
+	var templates strings.Builder
+
+	for _, pkiFile := range pkiFiles {
+		templateCommand := ""
+		if pkiFile.destination == pkiCertDestination { // Notify only once per renewal
+			templateCommand = notifyCmd
+		}
+
+		templateBlock = config.TemplateBlock
+		templateBlock = strings.Replace(templateBlock, pkiDestinationPlaceholder, pkiFile.destination, -1)
+		templateBlock = strings.Replace(templateBlock, pkiTemplateContentPlaceholder, "{{ with "+secret+" }}{{ .Data."+pkiFile.dataField+" }}{{ end }}", -1)
+		templateBlock = strings.Replace(templateBlock, pkiVaultPathPlaceholder, issuePath, -1)
+		templateBlock = strings.Replace(templateBlock, pkiTemplateCommandPlaceholder, templateCommand, -1)
+
+		templates.WriteString(templateBlock)
+		templates.WriteString("\n")
+	}
+
+	return &pkiModeConfig{issuePath, templates.String()}, nil
+}
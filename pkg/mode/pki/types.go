@@ -0,0 +1,36 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import ctx "talend/vault-sidecar-injector/pkg/context"
+
+var pkiContainerNames = map[string][]string{
+	ctx.JsonPathContainers: {pkiContainerName},
+}
+
+// Files rendered from the issued certificate: destination and field of the response's data
+var pkiFiles = [...]struct {
+	destination string
+	dataField   string
+}{
+	{pkiCertDestination, pkiCertDataField},
+	{pkiKeyDestination, pkiKeyDataField},
+	{pkiCADestination, pkiCADataField},
+}
+
+type pkiModeConfig struct {
+	issuePath string
+	template  string
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import (
+	"errors"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	m "talend/vault-sidecar-injector/pkg/mode"
+
+	"k8s.io/klog"
+)
+
+func getPKIModeConfig(config ctx.ModeConfig) (*pkiModeConfig, error) {
+	if config != nil {
+		pkiModeCfg, ok := config.(*pkiModeConfig) // here we use type assertion (https://golang.org/ref/spec#Type_assertions)
+
+		if ok {
+			return pkiModeCfg, nil
+		}
+
+		err := errors.New("Provided type cannot be casted to 'pkiModeConfig'")
+		klog.Error(err.Error())
+		return nil, err
+	}
+
+	err := errors.New("PKI mode config is null")
+	klog.Warning(err.Error())
+	return nil, err
+}
+
+// GetIssuePath : return Vault path used to issue certificate ("<pki mount>/issue/<pki role>") if pki mode is enabled, empty string otherwise
+func GetIssuePath(context *ctx.InjectionContext) string {
+	if context.ModesStatus[m.VaultInjectorModePKI] {
+		if pkiModeCfg, err := getPKIModeConfig(context.ModesConfig[m.VaultInjectorModePKI]); err == nil {
+			return pkiModeCfg.issuePath
+		}
+	}
+
+	return ""
+}
@@ -15,8 +15,6 @@
 package secrets
 
 import (
-	"errors"
-	"fmt"
 	"strings"
 	cfg "talend/vault-sidecar-injector/pkg/config"
 	ctx "talend/vault-sidecar-injector/pkg/context"
@@ -49,7 +47,7 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 		}
 
 		if !secretsTypeSupported {
-			err := fmt.Errorf("Submitted pod makes use of unsupported secrets type '%s'", secretsType)
+			err := ctx.NewInjectionError(ctx.ReasonUnsupportedSecretsType, "Submitted pod makes use of unsupported secrets type '%s'", secretsType)
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return nil, err
 		}
@@ -67,7 +65,7 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 		}
 
 		if !secretsInjectionMethodSupported {
-			err := fmt.Errorf("Submitted pod makes use of unsupported secrets injection method '%s'", secretsInjectionMethod)
+			err := ctx.NewInjectionError(ctx.ReasonUnsupportedInjectionMethod, "Submitted pod makes use of unsupported secrets injection method '%s'", secretsInjectionMethod)
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return nil, err
 		}
@@ -75,14 +73,14 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 
 	// If dynamic secrets and injection method is "env": return error (unsupported)
 	if secretsType == vaultInjectorSecretsTypeDynamic && secretsInjectionMethod == vaultInjectorSecretsInjectionMethodEnv {
-		err := fmt.Errorf("Submitted pod uses unsupported combination of secrets injection method '%s' with dynamic secrets", vaultInjectorSecretsInjectionMethodEnv)
+		err := ctx.NewInjectionError(ctx.ReasonUnsupportedCombination, "Submitted pod uses unsupported combination of secrets injection method '%s' with dynamic secrets", vaultInjectorSecretsInjectionMethodEnv)
 		klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 		return nil, err
 	}
 
 	// If authentication method is "approle" and static secrets: return error (unsupported because, as static secrets, approle needs initContainer)
 	if secretsType == vaultInjectorSecretsTypeStatic && annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationAuthMethodKey]] == ctx.VaultAppRoleAuthMethod {
-		err := fmt.Errorf("Submitted pod uses unsupported combination of Vault Auth Method '%s' with static secrets", ctx.VaultAppRoleAuthMethod)
+		err := ctx.NewInjectionError(ctx.ReasonUnsupportedCombination, "Submitted pod uses unsupported combination of Vault Auth Method '%s' with static secrets", ctx.VaultAppRoleAuthMethod)
 		klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 		return nil, err
 	}
@@ -92,7 +90,7 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 		applicationServiceLabel := labels[config.ApplicationServiceLabelKey]
 
 		if applicationLabel == "" || applicationServiceLabel == "" {
-			err := fmt.Errorf("Submitted pod must contain labels %s and %s", config.ApplicationLabelKey, config.ApplicationServiceLabelKey)
+			err := ctx.NewInjectionError(ctx.ReasonMissingLabel, "Submitted pod must contain labels %s and %s", config.ApplicationLabelKey, config.ApplicationServiceLabelKey)
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return nil, err
 		}
@@ -104,10 +102,12 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 		templateDest[0] = templateAppSvcDefaultDestination
 	}
 
-	if secretsTemplateNum == 1 && secretsTemplate[0] == "" {
+	customTemplate := !(secretsTemplateNum == 1 && secretsTemplate[0] == "")
+
+	if !customTemplate {
 		// We must have same numbers of secrets path & secrets destinations
 		if templateDestNum != secretsPathNum {
-			err := errors.New("Submitted pod must contain same numbers of secrets path and secrets destinations")
+			err := ctx.NewInjectionError(ctx.ReasonMismatchedSecretsCount, "Submitted pod must contain same numbers of secrets path and secrets destinations")
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return nil, err
 		}
@@ -120,7 +120,7 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 	} else {
 		// We must have same numbers of custom templates & secrets destinations ...
 		if templateDestNum != secretsTemplateNum {
-			err := errors.New("Submitted pod must contain same numbers of templates and secrets destinations")
+			err := ctx.NewInjectionError(ctx.ReasonMismatchedSecretsCount, "Submitted pod must contain same numbers of templates and secrets destinations")
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return nil, err
 		}
@@ -148,5 +148,5 @@ func secretsModeCompute(config *cfg.VSIConfig, labels, annotations map[string]st
 		templates.WriteString("\n")
 	}
 
-	return &secretsModeConfig{secretsType, secretsInjectionMethod, templates.String()}, nil
+	return &secretsModeConfig{secretsType, secretsInjectionMethod, secretsPath, customTemplate, templates.String()}, nil
 }
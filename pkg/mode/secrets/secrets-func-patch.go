@@ -15,9 +15,8 @@
 package secrets
 
 import (
-	"errors"
-	"fmt"
 	"path"
+	"reflect"
 	"strconv"
 	"strings"
 	cfg "talend/vault-sidecar-injector/pkg/config"
@@ -57,10 +56,16 @@ func patchCommand(podContainers []corev1.Container) (patch []ctx.PatchOperation,
 			secretsVolMountPath = SecretsDefaultMountPath
 		}
 
+		envProcess := path.Join(secretsVolMountPath, vaultInjectorEnvProcess)
+
 		// We currently require an explicit command to determine what is the process to run in the end
 		if podCnt.Command != nil {
+			if len(podCnt.Command) > 0 && podCnt.Command[0] == envProcess { // Command already patched by a previous invocation
+				continue
+			}
+
 			// Prepend existing command array with our specific env process
-			command := append([]string{path.Join(secretsVolMountPath, vaultInjectorEnvProcess)}, podCnt.Command...)
+			command := append([]string{envProcess}, podCnt.Command...)
 
 			// Here we have to use 'replace' JSON Patch operation
 			patch = append(patch, ctx.PatchOperation{
@@ -69,7 +74,7 @@ func patchCommand(podContainers []corev1.Container) (patch []ctx.PatchOperation,
 				Value: command,
 			})
 		} else {
-			err = fmt.Errorf("No explicit command found for container %s", podCnt.Name)
+			err = ctx.NewInjectionError(ctx.ReasonMissingCommand, "No explicit command found for container %s", podCnt.Name)
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return
 		}
@@ -85,14 +90,14 @@ func addLifecycleHooks(config *cfg.VSIConfig, podContainers []corev1.Container,
 	case "y", "yes", "true", "on":
 		// Check if job mode is enabled: this annotation should not be used then
 		if context.ModesStatus[m.VaultInjectorModeJob] {
-			err = fmt.Errorf("Submitted pod uses unsupported combination of '%s' annotation with '%s' mode", config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationLifecycleHookKey], m.VaultInjectorModeJob)
+			err = ctx.NewInjectionError(ctx.ReasonUnsupportedCombination, "Submitted pod uses unsupported combination of '%s' annotation with '%s' mode", config.VaultInjectorAnnotationsFQ[vaultInjectorAnnotationLifecycleHookKey], m.VaultInjectorModeJob)
 			klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 			return
 		}
 
 		if config.PodslifecycleHooks.PostStart != nil {
 			if config.PodslifecycleHooks.PostStart.Exec == nil {
-				err = errors.New("Unsupported lifecycle hook. Only support Exec type")
+				err = ctx.NewInjectionError(ctx.ReasonUnsupportedLifecycleHook, "Unsupported lifecycle hook. Only support Exec type")
 				klog.Errorf("[%s] %s", m.VaultInjectorModeSecrets, err.Error())
 				return
 			}
@@ -113,11 +118,15 @@ func addLifecycleHooks(config *cfg.VSIConfig, podContainers []corev1.Container,
 					hookCommand[commandIdx] = strings.Replace(hookCommand[commandIdx], secretsVolMountPathPlaceholder, secretsVolMountPath, -1)
 				}
 
-				postStartHook := &corev1.Handler{Exec: &corev1.ExecAction{Command: hookCommand}}
+				postStartHook := &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: hookCommand}}
 
 				if podCnt.Lifecycle != nil {
+					if reflect.DeepEqual(podCnt.Lifecycle.PostStart, postStartHook) { // Hook already added by a previous invocation
+						continue
+					}
+
 					if podCnt.Lifecycle.PostStart != nil {
-						klog.Warningf("[%s] Replacing existing postStart hook for container %s", m.VaultInjectorModeSecrets, podCnt.Name)
+						context.AddWarning("[%s] Replacing existing postStart hook for container %s", m.VaultInjectorModeSecrets, podCnt.Name)
 					}
 
 					podCnt.Lifecycle.PostStart = postStartHook
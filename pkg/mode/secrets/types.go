@@ -34,5 +34,7 @@ var secretsContainerNames = map[string][]string{
 type secretsModeConfig struct {
 	secretsType            string
 	secretsInjectionMethod string
+	secretsPath            []string // empty values when custom templates are provided
+	customTemplate         bool     // whether custom templates are provided (Vault paths are then unknown)
 	template               string
 }
@@ -58,6 +58,56 @@ func IsSecretsInjectionEnv(context *ctx.InjectionContext) bool {
 	return false
 }
 
+// GetSecretsType : return type of secrets (static or dynamic) if secrets mode is enabled, empty string otherwise
+func GetSecretsType(context *ctx.InjectionContext) string {
+	if context.ModesStatus[m.VaultInjectorModeSecrets] {
+		if secretsModeCfg, err := getSecretsModeConfig(context.ModesConfig[m.VaultInjectorModeSecrets]); err == nil {
+			return secretsModeCfg.secretsType
+		}
+	}
+
+	return ""
+}
+
+// GetSecretsInjectionMethod : return secrets injection method (file or env) if secrets mode is enabled, empty string otherwise
+func GetSecretsInjectionMethod(context *ctx.InjectionContext) string {
+	if context.ModesStatus[m.VaultInjectorModeSecrets] {
+		if secretsModeCfg, err := getSecretsModeConfig(context.ModesConfig[m.VaultInjectorModeSecrets]); err == nil {
+			return secretsModeCfg.secretsInjectionMethod
+		}
+	}
+
+	return ""
+}
+
+// GetSecretsPaths : return Vault paths of secrets if secrets mode is enabled (paths set in custom templates are not returned)
+func GetSecretsPaths(context *ctx.InjectionContext) []string {
+	var secretsPaths []string
+
+	if context.ModesStatus[m.VaultInjectorModeSecrets] {
+		if secretsModeCfg, err := getSecretsModeConfig(context.ModesConfig[m.VaultInjectorModeSecrets]); err == nil {
+			for _, secretsPath := range secretsModeCfg.secretsPath {
+				if secretsPath != "" {
+					secretsPaths = append(secretsPaths, secretsPath)
+				}
+			}
+		}
+	}
+
+	return secretsPaths
+}
+
+// IsSecretsCustomTemplate : return whether secrets mode is enabled with custom templates (Vault paths read by templates are then unknown)
+func IsSecretsCustomTemplate(context *ctx.InjectionContext) bool {
+	if context.ModesStatus[m.VaultInjectorModeSecrets] {
+		if secretsModeCfg, err := getSecretsModeConfig(context.ModesConfig[m.VaultInjectorModeSecrets]); err == nil {
+			return secretsModeCfg.customTemplate
+		}
+	}
+
+	return false
+}
+
 func getMountPathOfSecretsVolume(cnt corev1.Container) string {
 	var secretsVolMountPath string
 
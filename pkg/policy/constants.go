@@ -0,0 +1,28 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+const (
+	//--- Variables available in policy rules' expressions
+	VarNamespace      = "podNamespace"   // pod's namespace ("namespace" being a reserved CEL identifier)
+	VarServiceAccount = "serviceAccount" // pod's service account
+	VarLabels         = "labels"         // pod's labels
+	VarAnnotations    = "annotations"    // pod's annotations (including namespace defaults)
+	VarRole           = "role"           // Vault role
+	VarAuthMethod     = "authMethod"     // Vault Auth Method
+	VarSecretsPaths   = "secretsPaths"   // Vault secrets paths computed from pod's annotations
+	VarModes          = "modes"          // enabled injection modes
+	VarCustomTemplate = "customTemplate" // whether secrets are read by custom templates (their Vault paths are then not in secretsPaths)
+)
@@ -0,0 +1,138 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/klog"
+)
+
+// Compile : check and compile policy rules (to call once rules are loaded)
+func (policy *Policy) Compile() error {
+	env, err := cel.NewEnv(
+		cel.Variable(VarNamespace, cel.StringType),
+		cel.Variable(VarServiceAccount, cel.StringType),
+		cel.Variable(VarLabels, cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable(VarAnnotations, cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable(VarRole, cel.StringType),
+		cel.Variable(VarAuthMethod, cel.StringType),
+		cel.Variable(VarSecretsPaths, cel.ListType(cel.StringType)),
+		cel.Variable(VarModes, cel.ListType(cel.StringType)),
+		cel.Variable(VarCustomTemplate, cel.BoolType),
+	)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(policy.Rules))
+	policy.programs = make([]cel.Program, len(policy.Rules))
+
+	for idx, rule := range policy.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("policy rule #%d has no name", idx)
+		}
+
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate policy rule '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return fmt.Errorf("policy rule '%s': %v", rule.Name, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType {
+			return fmt.Errorf("policy rule '%s': expression must evaluate to a boolean, not %v", rule.Name, ast.OutputType())
+		}
+
+		if policy.programs[idx], err = env.Program(ast); err != nil {
+			return fmt.Errorf("policy rule '%s': %v", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate : check input against all rules, return an error naming the first failing rule (nil policy allows everything)
+func (policy *Policy) Evaluate(input *Input) error {
+	if policy == nil {
+		return nil
+	}
+
+	// Fail closed: rules on Vault paths could be bypassed if some paths are unknown
+	if input.UnknownPaths && !policy.AllowUnknownPaths {
+		err := ctx.NewInjectionError(ctx.ReasonPolicyViolation, "Policy denied injection: Vault paths cannot all be determined (custom templates or proxy mode) and unknown paths are not allowed")
+		klog.Error(err.Error())
+		return err
+	}
+
+	labels := input.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	annotations := input.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	secretsPaths := input.SecretsPaths
+	if secretsPaths == nil {
+		secretsPaths = []string{}
+	}
+
+	modes := input.Modes
+	if modes == nil {
+		modes = []string{}
+	}
+
+	vars := map[string]interface{}{
+		VarNamespace:      input.Namespace,
+		VarServiceAccount: input.ServiceAccount,
+		VarLabels:         labels,
+		VarAnnotations:    annotations,
+		VarRole:           input.Role,
+		VarAuthMethod:     input.AuthMethod,
+		VarSecretsPaths:   secretsPaths,
+		VarModes:          modes,
+		VarCustomTemplate: input.CustomTemplate,
+	}
+
+	for idx, rule := range policy.Rules {
+		out, _, err := policy.programs[idx].Eval(vars)
+		if err != nil {
+			err = ctx.NewInjectionError(ctx.ReasonPolicyViolation, "Policy rule '%s' failed to evaluate: %v", rule.Name, err)
+			klog.Error(err.Error())
+			return err
+		}
+
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			message := rule.Message
+			if message == "" {
+				message = rule.Expression
+			}
+
+			err = ctx.NewInjectionError(ctx.ReasonPolicyViolation, "Policy rule '%s' denied injection: %s", rule.Name, message)
+			klog.Error(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
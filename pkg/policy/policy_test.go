@@ -0,0 +1,107 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	tables := []struct {
+		name  string
+		rules []Rule
+		valid bool
+	}{
+		{"no rule", nil, true},
+		{"valid rule", []Rule{{Name: "r", Expression: "role == labels['app']"}}, true},
+		{"modes and custom template", []Rule{{Name: "r", Expression: "!customTemplate && !('proxy' in modes)"}}, true},
+		{"missing name", []Rule{{Expression: "true"}}, false},
+		{"duplicate name", []Rule{{Name: "r", Expression: "true"}, {Name: "r", Expression: "false"}}, false},
+		{"syntax error", []Rule{{Name: "r", Expression: "role =="}}, false},
+		{"unknown variable", []Rule{{Name: "r", Expression: "image == 'foo'"}}, false},
+		{"not a boolean", []Rule{{Name: "r", Expression: "size(secretsPaths)"}}, false},
+	}
+
+	for _, table := range tables {
+		err := (&Policy{Rules: table.rules}).Compile()
+		if table.valid {
+			assert.NoError(t, err, table.name)
+		} else {
+			assert.Error(t, err, table.name)
+		}
+	}
+
+	err := (&Policy{Rules: []Rule{{Name: "r", Expression: "role"}}}).Compile()
+	assert.ErrorContains(t, err, "must evaluate to a boolean")
+}
+
+func TestEvaluate(t *testing.T) {
+	policy := &Policy{
+		Rules: []Rule{
+			{
+				Name:       "team-a-secrets",
+				Expression: `podNamespace != "team-a" || secretsPaths.all(path, path.startsWith("secret/team-a/"))`,
+				Message:    "pods in namespace team-a may only read secrets under secret/team-a/",
+			},
+			{
+				Name:       "kubernetes-auth-only",
+				Expression: `authMethod == "kubernetes" && serviceAccount != "default"`,
+			},
+		},
+	}
+
+	if err := policy.Compile(); err != nil {
+		t.Fatalf("Compile error: %s", err)
+	}
+
+	tables := []struct {
+		name   string
+		input  Input
+		denyBy string
+	}{
+		{"allowed", Input{Namespace: "team-a", ServiceAccount: "app", AuthMethod: "kubernetes", SecretsPaths: []string{"secret/team-a/app"}}, ""},
+		{"other namespace", Input{Namespace: "team-b", ServiceAccount: "app", AuthMethod: "kubernetes", SecretsPaths: []string{"secret/team-a/app"}}, ""},
+		{"no secrets path", Input{Namespace: "team-a", ServiceAccount: "app", AuthMethod: "kubernetes"}, ""},
+		{"forbidden secrets path", Input{Namespace: "team-a", ServiceAccount: "app", AuthMethod: "kubernetes", SecretsPaths: []string{"secret/team-a/app", "secret/team-b/app"}}, "team-a-secrets"},
+		{"forbidden auth method", Input{Namespace: "team-a", ServiceAccount: "app", AuthMethod: "approle"}, "kubernetes-auth-only"},
+	}
+
+	for _, table := range tables {
+		err := policy.Evaluate(&table.input)
+		if table.denyBy == "" {
+			assert.NoError(t, err, table.name)
+		} else if assert.Error(t, err, table.name) {
+			assert.Equal(t, ctx.ReasonPolicyViolation, ctx.GetErrorReason(err), table.name)
+			assert.Contains(t, err.Error(), "'"+table.denyBy+"'", table.name)
+		}
+	}
+
+	// Unknown paths: denied unless allowed by policy
+	unknownPaths := &Input{Namespace: "team-a", ServiceAccount: "app", AuthMethod: "kubernetes", Modes: []string{"secrets"}, CustomTemplate: true, UnknownPaths: true}
+	err := policy.Evaluate(unknownPaths)
+	if assert.Error(t, err) {
+		assert.Equal(t, ctx.ReasonPolicyViolation, ctx.GetErrorReason(err))
+	}
+
+	policy.AllowUnknownPaths = true
+	assert.NoError(t, policy.Evaluate(unknownPaths))
+
+	// No policy: everything allowed
+	var noPolicy *Policy
+	assert.NoError(t, noPolicy.Evaluate(&Input{}))
+}
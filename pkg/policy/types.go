@@ -0,0 +1,47 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"github.com/google/cel-go/cel"
+)
+
+// Rule : named CEL expression that must evaluate to true for injection to be allowed
+type Rule struct {
+	Name       string `yaml:"name" json:"name"`
+	Expression string `yaml:"expression" json:"expression"`
+	Message    string `yaml:"message,omitempty" json:"message,omitempty"` // optional, returned when rule fails
+}
+
+// Policy : compiled rules
+type Policy struct {
+	Rules             []Rule `yaml:"rules" json:"rules"`
+	AllowUnknownPaths bool   `yaml:"allowUnknownPaths,omitempty" json:"allowUnknownPaths,omitempty"` // allow injections whose Vault paths cannot all be determined (custom templates, proxy mode)
+	programs          []cel.Program
+}
+
+// Input : values rules are evaluated against
+type Input struct {
+	Namespace      string
+	ServiceAccount string
+	Labels         map[string]string
+	Annotations    map[string]string
+	Role           string
+	AuthMethod     string
+	SecretsPaths   []string
+	Modes          []string
+	CustomTemplate bool
+	UnknownPaths   bool // whether Vault paths read by the pod cannot all be determined (not all in SecretsPaths)
+}
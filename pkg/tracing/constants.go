@@ -0,0 +1,33 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+// Tracer and service names
+const (
+	TracerName  = "talend/vault-sidecar-injector"
+	ServiceName = "vault-sidecar-injector"
+)
+
+// Span attributes
+const (
+	AttributeUID       = "k8s.admission.uid"
+	AttributeNamespace = "k8s.namespace.name"
+)
+
+// Standard OpenTelemetry env vars used to enable tracing when no endpoint is explicitly provided
+const (
+	envOTLPEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+)
@@ -0,0 +1,110 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog"
+)
+
+// Init sets up export of traces to an OTLP/HTTP endpoint. Provided endpoint URL takes precedence over standard
+// OpenTelemetry env vars. Tracing is disabled if no endpoint is set. Returned function flushes and stops tracing.
+func Init(endpoint, version string) (func(context.Context) error, error) {
+	if endpoint == "" && os.Getenv(envOTLPEndpoint) == "" && os.Getenv(envOTLPTracesEndpoint) == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		klog.Errorf("Failed to create OTLP trace exporter: %v", err)
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", ServiceName),
+		attribute.String("service.version", version),
+	))
+	if err != nil {
+		klog.Errorf("Failed to create trace resource: %v", err)
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{}) // to continue traces started by the API server
+
+	klog.Info("OTLP tracing enabled")
+	return tracerProvider.Shutdown, nil
+}
+
+// FromRequest returns request's context, along with trace context propagated by the caller if any
+func FromRequest(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+// WithAttributes returns a context carrying attributes to set on all spans started from it
+func WithAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	current, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+
+	merged := make([]attribute.KeyValue, 0, len(current)+len(attrs))
+	merged = append(merged, current...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// Start starts a span carrying attributes from context
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	attrs, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, recording error if any
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// RequestAttributes returns attributes identifying an admission request
+func RequestAttributes(uid, namespace string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(AttributeUID, uid),
+		attribute.String(AttributeNamespace, namespace),
+	}
+}
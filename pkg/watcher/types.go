@@ -0,0 +1,28 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// FileWatcher : watch files content and call handler on change
+type FileWatcher struct {
+	files    []string                     // files to watch
+	interval time.Duration                // polling interval
+	onChange func()                       // handler called when content of at least one file changed
+	hashes   map[string][sha256.Size]byte // last known hash of each watched file
+}
@@ -0,0 +1,83 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// New : init file watcher.
+//
+// Files are polled and their content compared with previous hash. Reading through symlinks, this approach also handles
+// updates of mounted ConfigMaps and Secrets (where Kubernetes atomically swaps a '..data' symlink).
+func New(files []string, interval time.Duration, onChange func()) *FileWatcher {
+	fw := &FileWatcher{
+		files:    files,
+		interval: interval,
+		onChange: onChange,
+		hashes:   make(map[string][sha256.Size]byte, len(files)),
+	}
+
+	// Get initial state
+	fw.changed()
+
+	return fw
+}
+
+// Start : watch files in new routine until stop channel is closed
+func (fw *FileWatcher) Start(stopCh <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(fw.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if fw.changed() {
+					fw.onChange()
+				}
+			}
+		}
+	}()
+}
+
+func (fw *FileWatcher) changed() bool {
+	changed := false
+
+	for _, file := range fw.files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			// File may be missing while being replaced: keep last known state and retry on next tick
+			klog.Warningf("Failed to read watched file %s: %v", file, err)
+			continue
+		}
+
+		hash := sha256.Sum256(data)
+		if previousHash, ok := fw.hashes[file]; ok && previousHash != hash {
+			klog.Infof("Watched file %s changed [sha256sum: %x]", file, hash)
+			changed = true
+		}
+
+		fw.hashes[file] = hash
+	}
+
+	return changed
+}
@@ -39,7 +39,12 @@ func Convert_v1beta1_AdmissionReview_To_admission_AdmissionReview(in *admv1beta1
 	} else {
 		out.Request = nil
 	}
-	out.Response = (*admv1.AdmissionResponse)(unsafe.Pointer(in.Response))
+	if in.Response != nil {
+		out.Response = new(admv1.AdmissionResponse)
+		Convert_v1beta1_AdmissionResponse_To_admission_AdmissionResponse(in.Response, out.Response)
+	} else {
+		out.Response = nil
+	}
 }
 
 func Convert_v1beta1_AdmissionRequest_To_admission_AdmissionRequest(in *admv1beta1.AdmissionRequest, out *admv1.AdmissionRequest) {
@@ -69,7 +74,12 @@ func Convert_admission_AdmissionReview_To_v1beta1_AdmissionReview(in *admv1.Admi
 	} else {
 		out.Request = nil
 	}
-	out.Response = (*admv1beta1.AdmissionResponse)(unsafe.Pointer(in.Response))
+	if in.Response != nil {
+		out.Response = new(admv1beta1.AdmissionResponse)
+		Convert_admission_AdmissionResponse_To_v1beta1_AdmissionResponse(in.Response, out.Response)
+	} else {
+		out.Response = nil
+	}
 }
 
 func Convert_admission_AdmissionRequest_To_v1beta1_AdmissionRequest(in *admv1.AdmissionRequest, out *admv1beta1.AdmissionRequest) {
@@ -87,3 +97,24 @@ func Convert_admission_AdmissionRequest_To_v1beta1_AdmissionRequest(in *admv1.Ad
 	out.OldObject = in.OldObject
 	out.Options = in.Options
 }
+
+func Convert_v1beta1_AdmissionResponse_To_admission_AdmissionResponse(in *admv1beta1.AdmissionResponse, out *admv1.AdmissionResponse) {
+	out.UID = types.UID(in.UID)
+	out.Allowed = in.Allowed
+	out.Result = in.Result
+	out.Patch = in.Patch
+	out.PatchType = (*admv1.PatchType)(unsafe.Pointer(in.PatchType))
+	out.AuditAnnotations = in.AuditAnnotations
+	out.Warnings = in.Warnings
+}
+
+func Convert_admission_AdmissionResponse_To_v1beta1_AdmissionResponse(in *admv1.AdmissionResponse, out *admv1beta1.AdmissionResponse) {
+	out.UID = types.UID(in.UID)
+	out.Allowed = in.Allowed
+	out.Result = in.Result
+	out.Patch = in.Patch
+	out.PatchType = (*admv1beta1.PatchType)(unsafe.Pointer(in.PatchType))
+	out.AuditAnnotations = in.AuditAnnotations
+	// Warnings are only displayed by clients for Kubernetes 1.19+ (field is ignored by older API servers)
+	out.Warnings = in.Warnings
+}
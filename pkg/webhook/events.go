@@ -0,0 +1,56 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	ctx "talend/vault-sidecar-injector/pkg/context"
+
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Emit a Kubernetes Event against pod's controller (or namespace if no controller) when injection is denied
+func (vaultInjector *VaultInjector) recordDenial(req *admv1.AdmissionRequest, pod *corev1.Pod, podNamespace string, err error) {
+	if vaultInjector.EventRecorder == nil {
+		return
+	}
+
+	// Dry run requests must not have side effects
+	if req.DryRun != nil && *req.DryRun {
+		return
+	}
+
+	var target *corev1.ObjectReference
+
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		target = &corev1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			Namespace:  podNamespace,
+			UID:        owner.UID,
+		}
+	} else {
+		target = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       podNamespace,
+			Namespace:  podNamespace, // Event is created in this namespace (in 'default' namespace if not set)
+		}
+	}
+
+	vaultInjector.EventRecorder.Eventf(target, corev1.EventTypeWarning, ctx.GetErrorReason(err), "Vault Sidecar Injector denied injection: %s", err.Error())
+}
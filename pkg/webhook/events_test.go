@@ -0,0 +1,81 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Fake recorder also keeping objects events are about
+type objectRecorder struct {
+	*record.FakeRecorder
+	objects []runtime.Object
+}
+
+func (r *objectRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.objects = append(r.objects, object)
+	r.FakeRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
+
+func TestRecordDenial(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	recorder := &objectRecorder{FakeRecorder: record.NewFakeRecorder(10)}
+	vaultInjector.EventRecorder = recorder
+
+	// Pod without container
+	ar, err := (&testResource{manifest: "../../test/workloads/ko/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	ar.Request.Namespace = "team-a"
+
+	resp := vaultInjector.mutate(gocontext.Background(), ar)
+	assert.False(t, resp.Allowed)
+
+	if assert.Len(t, recorder.Events, 1) {
+		event := <-recorder.Events
+		assert.Contains(t, event, "Warning NoContainer")
+		assert.Contains(t, event, resp.Result.Message)
+	}
+
+	// Pod without controller: event against its namespace, created in this namespace
+	if assert.Len(t, recorder.objects, 1) {
+		target, ok := recorder.objects[0].(*corev1.ObjectReference)
+		if assert.True(t, ok) {
+			assert.Equal(t, "Namespace", target.Kind)
+			assert.Equal(t, "team-a", target.Name)
+			assert.Equal(t, "team-a", target.Namespace)
+		}
+	}
+
+	// No event for dry run requests
+	dryRun := true
+	ar.Request.DryRun = &dryRun
+
+	resp = vaultInjector.mutate(gocontext.Background(), ar)
+	assert.False(t, resp.Allowed)
+	assert.Len(t, recorder.Events, 0)
+}
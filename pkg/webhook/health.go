@@ -0,0 +1,102 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// Healthz : liveness endpoint, only tells that the server is able to respond
+func (vaultInjector *VaultInjector) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+// Readyz : readiness endpoint, fails if server cannot properly handle admission requests
+func (vaultInjector *VaultInjector) Readyz(w http.ResponseWriter, r *http.Request) {
+	if err := vaultInjector.ready(); err != nil {
+		klog.Warningf("Readiness check failed: %v", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
+
+// SetShuttingDown : flag server as shutting down (readiness will then fail)
+func (vaultInjector *VaultInjector) SetShuttingDown() {
+	atomic.StoreInt32(&vaultInjector.shuttingDown, 1)
+}
+
+func (vaultInjector *VaultInjector) ready() error {
+	if atomic.LoadInt32(&vaultInjector.shuttingDown) == 1 {
+		return errors.New("Server is shutting down")
+	}
+
+	if err := vaultInjector.Config().Validate(); err != nil {
+		return fmt.Errorf("Invalid configuration: %v", err)
+	}
+
+	cert, err := vaultInjector.certificate()
+	if err != nil {
+		return fmt.Errorf("Invalid certificate: %v", err)
+	}
+
+	if cert != nil {
+		now := time.Now()
+
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("Certificate expired on %v", cert.NotAfter)
+		}
+
+		if now.Add(vaultInjector.CertExpiryThreshold).After(cert.NotAfter) {
+			return fmt.Errorf("Certificate expires soon (%v)", cert.NotAfter)
+		}
+	}
+
+	return nil
+}
+
+// Return webhook certificate currently used for TLS (nil if no TLS config)
+func (vaultInjector *VaultInjector) certificate() (*x509.Certificate, error) {
+	var tlsCert *tls.Certificate
+	var err error
+
+	if vaultInjector.Server == nil || vaultInjector.Server.TLSConfig == nil {
+		return nil, nil
+	}
+
+	tlsConfig := vaultInjector.Server.TLSConfig
+	if tlsConfig.GetCertificate != nil {
+		if tlsCert, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+			return nil, err
+		}
+	} else if len(tlsConfig.Certificates) > 0 {
+		tlsCert = &tlsConfig.Certificates[0]
+	}
+
+	if tlsCert == nil || len(tlsCert.Certificate) == 0 {
+		return nil, errors.New("No certificate loaded")
+	}
+
+	return x509.ParseCertificate(tlsCert.Certificate[0])
+}
@@ -0,0 +1,97 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"talend/vault-sidecar-injector/pkg/certs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	bundle, err := (&certs.Cert{CN: "Vault Sidecar Injector", Hosts: []string{"localhost"}, CALifetime: 24 * time.Hour}).GenerateWebhookBundle()
+	if err != nil {
+		t.Fatalf("Failed to generate webhook bundle: %v", err)
+	}
+
+	tlsCert, err := tls.X509KeyPair(bundle.Cert, bundle.PrivKey)
+	if err != nil {
+		t.Fatalf("Failed to load key pair: %v", err)
+	}
+
+	tables := []struct {
+		name                string
+		certExpiryThreshold time.Duration
+		invalidConfig       bool
+		shuttingDown        bool
+		statusCode          int
+	}{
+		{
+			name:       "Ready",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:                "Certificate expires soon",
+			certExpiryThreshold: 2 * 365 * 24 * time.Hour,
+			statusCode:          http.StatusServiceUnavailable,
+		},
+		{
+			name:          "Invalid configuration",
+			invalidConfig: true,
+			statusCode:    http.StatusServiceUnavailable,
+		},
+		{
+			name:         "Shutting down",
+			shuttingDown: true,
+			statusCode:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			vaultInjector, err := createTestVaultInjector()
+			if err != nil {
+				t.Fatalf("Loading error: %s", err)
+			}
+
+			vaultInjector.Server = &http.Server{TLSConfig: &tls.Config{Certificates: []tls.Certificate{tlsCert}}}
+			vaultInjector.CertExpiryThreshold = table.certExpiryThreshold
+
+			if table.invalidConfig {
+				invalidConfig := *vaultInjector.Config()
+				invalidConfig.TemplateBlock = ""
+				vaultInjector.setConfig(&invalidConfig)
+			}
+
+			if table.shuttingDown {
+				vaultInjector.SetShuttingDown()
+			}
+
+			responseRecorder := httptest.NewRecorder()
+			vaultInjector.Healthz(responseRecorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+			responseRecorder = httptest.NewRecorder()
+			vaultInjector.Readyz(responseRecorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, table.statusCode, responseRecorder.Code)
+		})
+	}
+}
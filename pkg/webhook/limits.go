@@ -0,0 +1,87 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"io"
+	"net/http"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// SetMaxConcurrentRequests : limit number of admission requests processed at the same time (no limit if max <= 0)
+func (vaultInjector *VaultInjector) SetMaxConcurrentRequests(max int) {
+	if max > 0 {
+		vaultInjector.requestSlots = make(chan struct{}, max)
+	} else {
+		vaultInjector.requestSlots = nil
+	}
+}
+
+// Take a slot to process an admission request, return false right away if none is available
+func (vaultInjector *VaultInjector) acquireRequestSlot() bool {
+	if vaultInjector.requestSlots == nil {
+		return true
+	}
+
+	select {
+	case vaultInjector.requestSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (vaultInjector *VaultInjector) releaseRequestSlot() {
+	if vaultInjector.requestSlots != nil {
+		<-vaultInjector.requestSlots
+	}
+}
+
+// Limit size of request body (no limit if MaxRequestBodySize <= 0)
+func (vaultInjector *VaultInjector) limitBody(w http.ResponseWriter, body io.ReadCloser) io.ReadCloser {
+	if vaultInjector.MaxRequestBodySize <= 0 {
+		return body
+	}
+
+	return http.MaxBytesReader(w, body, vaultInjector.MaxRequestBodySize)
+}
+
+// Return context ending when the timeout provided by the API server on webhook call ('timeout' query parameter) expires
+func requestContext(goctx gocontext.Context, r *http.Request) (gocontext.Context, gocontext.CancelFunc) {
+	if timeout := r.URL.Query().Get("timeout"); timeout != "" {
+		if duration, err := time.ParseDuration(timeout); err == nil && duration > 0 {
+			return gocontext.WithTimeout(goctx, duration)
+		}
+
+		klog.Warningf("Ignore invalid admission request timeout: %s", timeout)
+	}
+
+	return gocontext.WithCancel(goctx)
+}
+
+// Return an error if admission request processing must be aborted (timeout expired: API server no longer waits for the response)
+func checkDeadline(goctx gocontext.Context) error {
+	if err := goctx.Err(); err != nil {
+		err = ctx.NewInjectionError(ctx.ReasonTimeout, "Admission request processing aborted: %v", err)
+		klog.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
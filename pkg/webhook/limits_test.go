@@ -0,0 +1,117 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeLimits(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	body, err := json.Marshal(ar)
+	if err != nil {
+		t.Fatalf("Error marshalling AR: %s", err)
+	}
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		responseRecorder := httptest.NewRecorder()
+		vaultInjector.Serve(responseRecorder, request)
+		return responseRecorder
+	}
+
+	// No limit
+	assert.Equal(t, http.StatusOK, serve("/mutate").Code)
+
+	// Body too large
+	vaultInjector.MaxRequestBodySize = int64(len(body) - 1)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve("/mutate").Code)
+
+	vaultInjector.MaxRequestBodySize = int64(len(body))
+	assert.Equal(t, http.StatusOK, serve("/mutate").Code)
+
+	// Too many concurrent requests
+	vaultInjector.SetMaxConcurrentRequests(1)
+	assert.True(t, vaultInjector.acquireRequestSlot())
+	assert.Equal(t, http.StatusTooManyRequests, serve("/mutate").Code)
+
+	vaultInjector.releaseRequestSlot()
+	assert.Equal(t, http.StatusOK, serve("/mutate").Code)
+
+	// Expired timeout: injection is aborted
+	responseRecorder := serve("/mutate?timeout=1ns")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "Admission request processing aborted")
+}
+
+func TestServeMissingRequest(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	for _, body := range []string{
+		`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+		`{"apiVersion":"admission.k8s.io/v1beta1","kind":"AdmissionReview"}`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader([]byte(body)))
+		request.Header.Add("Content-Type", "application/json")
+		responseRecorder := httptest.NewRecorder()
+
+		assert.NotPanics(t, func() { vaultInjector.Serve(responseRecorder, request) }, body)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, body)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	tables := []struct {
+		target      string
+		hasDeadline bool
+	}{
+		{"/mutate", false},
+		{"/mutate?timeout=10s", true},
+		{"/mutate?timeout=invalid", false},
+	}
+
+	for _, table := range tables {
+		goctx, cancel := requestContext(gocontext.Background(), httptest.NewRequest(http.MethodPost, table.target, nil))
+		deadline, hasDeadline := goctx.Deadline()
+		assert.Equal(t, table.hasDeadline, hasDeadline, table.target)
+		if hasDeadline {
+			assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+		}
+
+		cancel()
+		assert.Error(t, goctx.Err(), table.target)
+	}
+}
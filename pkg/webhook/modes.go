@@ -16,6 +16,7 @@ package webhook
 
 import (
 	_ "talend/vault-sidecar-injector/pkg/mode/job"     // blank import to init job mode (registration)
+	_ "talend/vault-sidecar-injector/pkg/mode/pki"     // blank import to init pki mode (registration)
 	_ "talend/vault-sidecar-injector/pkg/mode/proxy"   // blank import to init proxy mode (registration)
 	_ "talend/vault-sidecar-injector/pkg/mode/secrets" // blank import to init secrets mode (registration)
 )
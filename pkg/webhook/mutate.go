@@ -15,8 +15,17 @@
 package webhook
 
 import (
+	gocontext "context"
 	"encoding/json"
+	"strings"
+	"talend/vault-sidecar-injector/pkg/audit"
+	cfg "talend/vault-sidecar-injector/pkg/config"
 	ctx "talend/vault-sidecar-injector/pkg/context"
+	"talend/vault-sidecar-injector/pkg/metrics"
+	m "talend/vault-sidecar-injector/pkg/mode"
+	"talend/vault-sidecar-injector/pkg/mode/secrets"
+	"talend/vault-sidecar-injector/pkg/tracing"
+	"time"
 
 	admv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -24,14 +33,27 @@ import (
 	"k8s.io/klog"
 )
 
-func (vaultInjector *VaultInjector) mutate(ar *admv1.AdmissionReview) *admv1.AdmissionResponse {
+func (vaultInjector *VaultInjector) mutate(goctx gocontext.Context, ar *admv1.AdmissionReview) *admv1.AdmissionResponse {
 	var pod corev1.Pod
 	var podName, podNamespace string
 
+	// Use same config during the whole mutation, even if a new one is loaded meanwhile
+	config := vaultInjector.Config()
+
+	defer metrics.ObserveDuration(metrics.HandlerMutate, time.Now())
+
 	req := ar.Request
 
+	// Record admission decision in audit log (fields are set along the way)
+	auditRecord := &audit.Record{UID: string(req.UID), Namespace: req.Namespace}
+	defer vaultInjector.AuditLogger.Log(auditRecord)
+
 	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
 		klog.Errorf("Could not unmarshal raw object: %v", err)
+		metrics.Denied(req.Namespace, ctx.ReasonInvalidObject)
+		auditRecord.Decision = audit.DecisionDenied
+		auditRecord.Reason = ctx.ReasonInvalidObject
+		auditRecord.Error = err.Error()
 		return &admv1.AdmissionResponse{
 			UID: req.UID,
 			Result: &metav1.Status{
@@ -50,27 +72,66 @@ func (vaultInjector *VaultInjector) mutate(ar *admv1.AdmissionReview) *admv1.Adm
 		podName = pod.Name
 	}
 
-	if pod.Namespace == "" {
-		podNamespace = metav1.NamespaceDefault
-	} else {
+	if pod.Namespace != "" {
 		podNamespace = pod.Namespace
+	} else if req.Namespace != "" { // Pods created by controllers do not carry their namespace
+		podNamespace = req.Namespace
+	} else {
+		podNamespace = metav1.NamespaceDefault
 	}
 
 	klog.Infof("AdmissionReview '%v' for '%+v', Namespace=%v Name='%v (%s/%s)' UID=%v patchOperation=%v",
 		ar.GroupVersionKind(), req.Kind, req.Namespace, req.Name, podNamespace, podName, req.UID, req.Operation)
 
+	// Identify request on all spans to come
+	goctx = tracing.WithAttributes(goctx, tracing.RequestAttributes(string(req.UID), podNamespace)...)
+
+	auditRecord.Namespace = podNamespace
+	auditRecord.PodName = podName
+	if owner := metav1.GetControllerOf(&pod); owner != nil {
+		auditRecord.Owner = &audit.Owner{Kind: owner.Kind, Name: owner.Name}
+	}
+
+	// Apply default annotations from namespace, if any (keep pod's own annotations to patch them)
+	podAnnotations := pod.Annotations
+	pod.Annotations = vaultInjector.mergeNamespaceDefaults(config, podNamespace, pod.Annotations)
+
 	// Determine whether to perform mutation
-	if !mutationRequired(ignoredNamespaces, vaultInjector.VaultInjectorAnnotationsFQ, &pod.ObjectMeta) {
-		klog.Infof("Skipping mutation for %s/%s due to policy check", podNamespace, podName)
+	if required, reason := mutationRequired(append(ignoredNamespaces, config.SkipNamespaces...), config.VaultInjectorAnnotationsFQ, podNamespace, &pod.ObjectMeta); !required {
+		klog.Infof("Skipping mutation for %s/%s due to policy check (%s)", podNamespace, podName, reason)
+		metrics.Skipped(podNamespace, reason)
+		auditRecord.Decision = audit.DecisionSkipped
+		auditRecord.Reason = reason
 		return &admv1.AdmissionResponse{
 			UID:     req.UID,
 			Allowed: true,
 		}
 	}
 
-	annotations := map[string]string{vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationStatusKey]: ctx.VaultInjectorStatusInjected}
-	patchBytes, err := vaultInjector.createPatch(&pod, annotations)
+	if requestedModes := pod.Annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationModeKey]]; requestedModes != "" {
+		auditRecord.RequestedModes = strings.Split(requestedModes, ",")
+	}
+
+	annotations := map[string]string{config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationStatusKey]: ctx.VaultInjectorStatusInjected}
+	patchBytes, context, err := vaultInjector.createPatch(goctx, config, &pod, podAnnotations, annotations)
+	if err == nil {
+		err = checkPolicy(goctx, config, &pod, podNamespace, context)
+	}
+
+	// Also audit requested Vault role and paths of denied injections (e.g. by policy), as long as they have been computed
+	if context != nil {
+		auditRecord.EffectiveModes = m.GetEnabledModes(context.ModesStatus)
+		auditRecord.VaultRole = context.VaultRole
+		auditRecord.VaultAuthMethod = context.VaultAuthMethod
+		auditRecord.SecretsPaths = getVaultPaths(context)
+	}
+
 	if err != nil {
+		metrics.Denied(podNamespace, ctx.GetErrorReason(err))
+		vaultInjector.recordDenial(req, &pod, podNamespace, err)
+		auditRecord.Decision = audit.DecisionDenied
+		auditRecord.Reason = ctx.GetErrorReason(err)
+		auditRecord.Error = err.Error()
 		return &admv1.AdmissionResponse{
 			UID:     req.UID,
 			Allowed: false,
@@ -80,11 +141,16 @@ func (vaultInjector *VaultInjector) mutate(ar *admv1.AdmissionReview) *admv1.Adm
 		}
 	}
 
+	metrics.Injected(podNamespace, context.ModesStatus, context.VaultAuthMethod, secrets.GetSecretsType(context), secrets.GetSecretsInjectionMethod(context))
+	auditRecord.InjectedContainers = context.InjectedContainers
+	auditRecord.Decision = audit.DecisionInjected
+
 	klog.Infof("AdmissionResponse: patch=%v\n", string(patchBytes))
 	return &admv1.AdmissionResponse{
-		UID:     req.UID,
-		Allowed: true,
-		Patch:   patchBytes,
+		UID:      req.UID,
+		Allowed:  true,
+		Patch:    patchBytes,
+		Warnings: context.Warnings,
 		PatchType: func() *admv1.PatchType {
 			pt := admv1.PatchTypeJSONPatch
 			return &pt
@@ -93,17 +159,34 @@ func (vaultInjector *VaultInjector) mutate(ar *admv1.AdmissionReview) *admv1.Adm
 }
 
 // Create mutation patch for resources
-func (vaultInjector *VaultInjector) createPatch(pod *corev1.Pod, annotations map[string]string) ([]byte, error) {
+func (vaultInjector *VaultInjector) createPatch(goctx gocontext.Context, config *cfg.VSIConfig, pod *corev1.Pod, podAnnotations, annotations map[string]string) ([]byte, *ctx.InjectionContext, error) {
+	// Compute mutation on pod freed of what a previous invocation may have injected: mutation is then idempotent
+	cleanedPod, reinvoked := removeInjected(config, pod)
+
+	patchPodSpec, context, err := vaultInjector.updatePodSpec(goctx, config, cleanedPod)
+	if err == nil {
+		err = checkDeadline(goctx)
+	}
 
-	patchPodSpec, err := vaultInjector.updatePodSpec(pod)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if reinvoked {
+		klog.Infof("Pod already contains injected resources: reconcile them")
+		if patchPodSpec, err = reconcilePodSpec(pod, cleanedPod, patchPodSpec); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	var patch []ctx.PatchOperation
+	patch := []ctx.PatchOperation{}
 
 	patch = append(patch, patchPodSpec...)
-	patch = append(patch, updateAnnotation(pod.Annotations, annotations)...)
+	patch = append(patch, updateAnnotation(podAnnotations, annotations)...)
+
+	_, span := tracing.Start(goctx, "marshalPatch")
+	patchBytes, err := json.Marshal(patch)
+	tracing.End(span, err)
 
-	return json.Marshal(patch)
+	return patchBytes, context, err
 }
@@ -15,6 +15,7 @@
 package webhook
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -23,13 +24,17 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"talend/vault-sidecar-injector/pkg/audit"
 	cfg "talend/vault-sidecar-injector/pkg/config"
 	ctx "talend/vault-sidecar-injector/pkg/context"
+	"talend/vault-sidecar-injector/pkg/policy"
 	"testing"
 
 	"k8s.io/apimachinery/pkg/util/uuid"
 
 	admv1 "k8s.io/api/admission/v1"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -100,6 +105,153 @@ func TestMutateKO(t *testing.T) {
 	}
 }
 
+func TestMutateAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	auditFile := filepath.Join(dir, "audit.log")
+	if vaultInjector.AuditLogger, err = audit.New(auditFile); err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+
+	for _, workloadManifest := range []string{"../../test/workloads/ok/test-app-dep-1.yaml", "../../test/workloads/ko/test-app-dep-1.yaml"} {
+		ar, err := (&testResource{manifest: workloadManifest}).load()
+		if err != nil {
+			t.Fatalf("Error creating AR: %s", err)
+		}
+
+		vaultInjector.mutate(gocontext.Background(), ar)
+	}
+
+	vaultInjector.AuditLogger.Close()
+
+	data, err := ioutil.ReadFile(auditFile)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	var injected, denied audit.Record
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &injected))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &denied))
+
+	assert.Equal(t, audit.DecisionInjected, injected.Decision)
+	assert.NotEmpty(t, injected.UID)
+	assert.NotEmpty(t, injected.EffectiveModes)
+	assert.NotEmpty(t, injected.VaultRole)
+	assert.NotEmpty(t, injected.SecretsPaths)
+	assert.NotEmpty(t, injected.InjectedContainers)
+	assert.Empty(t, injected.Error)
+
+	assert.Equal(t, audit.DecisionDenied, denied.Decision)
+	assert.NotEmpty(t, denied.Error)
+}
+
+func TestMutateAuditPolicyDenied(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	if vaultInjector.AuditLogger, err = audit.New(auditFile); err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+
+	config := vaultInjector.Config()
+	config.Policy = &policy.Policy{Rules: []policy.Rule{{Name: "forbidden-role", Expression: `role != "test"`}}}
+	if err = config.Policy.Compile(); err != nil {
+		t.Fatalf("Compile error: %s", err)
+	}
+
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	resp := vaultInjector.mutate(gocontext.Background(), ar)
+	assert.False(t, resp.Allowed)
+
+	vaultInjector.AuditLogger.Close()
+
+	data, err := ioutil.ReadFile(auditFile)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+
+	var denied audit.Record
+	if !assert.NoError(t, json.Unmarshal(data, &denied)) {
+		return
+	}
+
+	// What was requested is audited even though injection is denied
+	assert.Equal(t, audit.DecisionDenied, denied.Decision)
+	assert.Equal(t, ctx.ReasonPolicyViolation, denied.Reason)
+	assert.Equal(t, []string{"secrets"}, denied.EffectiveModes)
+	assert.Equal(t, "test", denied.VaultRole)
+	assert.Equal(t, "kubernetes", denied.VaultAuthMethod)
+	assert.Equal(t, []string{"secret/test/test-app-svc"}, denied.SecretsPaths)
+	assert.Empty(t, denied.InjectedContainers)
+}
+
+func TestMutateWarnings(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	// Deprecated 'workload' annotation
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-job-5.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	resp := vaultInjector.mutate(gocontext.Background(), ar)
+	if assert.True(t, resp.Allowed) && assert.Len(t, resp.Warnings, 1) {
+		assert.Contains(t, resp.Warnings[0], "is deprecated")
+	}
+
+	// Unknown mode and existing postStart hook replaced
+	tr := &testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}
+	if _, err = tr.load(); err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	tr.podTemplateSpec.Annotations["sidecar.vault.talend.org/mode"] = "secrets,unknown"
+	tr.podTemplateSpec.Annotations["sidecar.vault.talend.org/secrets-hook"] = "true"
+	tr.podTemplateSpec.Spec.Containers[0].Lifecycle = &corev1.Lifecycle{
+		PostStart: &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"true"}}},
+	}
+
+	if ar, err = tr.createAdmissionReview(); err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	resp = vaultInjector.mutate(gocontext.Background(), ar)
+	if assert.True(t, resp.Allowed) && assert.Len(t, resp.Warnings, 2) {
+		assert.Contains(t, resp.Warnings[0], "Ignore unknown requested Vault Sidecar Injector mode: unknown")
+		assert.Contains(t, resp.Warnings[1], "Replacing existing postStart hook for container test-app-container")
+	}
+
+	// Warnings are kept when responding with AdmissionReview v1beta1
+	arOutv1beta1 := &admv1beta1.AdmissionReview{}
+	Convert_admission_AdmissionReview_To_v1beta1_AdmissionReview(&admv1.AdmissionReview{Response: resp}, arOutv1beta1)
+	assert.Equal(t, resp.Warnings, arOutv1beta1.Response.Warnings)
+	assert.Equal(t, resp.Patch, arOutv1beta1.Response.Patch)
+}
+
 func mutateWorkloads(manifestsPattern string, test assertFunc) error {
 	verbose, _ := strconv.ParseBool(os.Getenv("VERBOSE"))
 	if verbose {
@@ -132,7 +284,7 @@ func mutateWorkloads(manifestsPattern string, test assertFunc) error {
 		}
 
 		// Mutate pod and test result
-		test(vaultInjector.mutate(ar))
+		test(vaultInjector.mutate(gocontext.Background(), ar))
 	}
 
 	return nil
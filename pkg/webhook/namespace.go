@@ -0,0 +1,64 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	cfg "talend/vault-sidecar-injector/pkg/config"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+
+	"k8s.io/klog"
+)
+
+// Merge default annotations set on pod's namespace under pod's own annotations (values provided by the pod always win)
+func (vaultInjector *VaultInjector) mergeNamespaceDefaults(config *cfg.VSIConfig, namespace string, annotations map[string]string) map[string]string {
+	if vaultInjector.NamespaceLister == nil {
+		return annotations
+	}
+
+	ns, err := vaultInjector.NamespaceLister.Get(namespace)
+	if err != nil {
+		klog.Warningf("Failed to get namespace %s, ignore namespace default annotations: %v", namespace, err)
+		return annotations
+	}
+
+	merged := make(map[string]string, len(annotations))
+
+	for _, vaultAnnotationKey := range vaultInjectorAnnotationKeys {
+		if vaultAnnotationKey == ctx.VaultInjectorAnnotationStatusKey { // Output annotation: no default
+			continue
+		}
+
+		defaultKey := ctx.VaultInjectorNamespaceDefaultPrefix + vaultAnnotationKey
+		if config.VaultInjectorAnnotationKeyPrefix != "" {
+			defaultKey = config.VaultInjectorAnnotationKeyPrefix + "/" + defaultKey
+		}
+
+		if value, ok := ns.Annotations[defaultKey]; ok {
+			merged[config.VaultInjectorAnnotationsFQ[vaultAnnotationKey]] = value
+		}
+	}
+
+	if len(merged) == 0 {
+		return annotations
+	}
+
+	klog.Infof("Default annotations from namespace %s: %+v", namespace, merged)
+
+	for key, value := range annotations {
+		merged[key] = value
+	}
+
+	return merged
+}
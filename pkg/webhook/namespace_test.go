@@ -0,0 +1,87 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"encoding/json"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestMutateNamespaceDefaults(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+			Annotations: map[string]string{
+				"sidecar.vault.talend.org/default-inject":       "true",
+				"sidecar.vault.talend.org/default-role":         "team-a",
+				"sidecar.vault.talend.org/default-secrets-path": "secret/team-a/common",
+			},
+		},
+	})
+	indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}})
+	vaultInjector.NamespaceLister = corelisters.NewNamespaceLister(indexer)
+
+	// Pod without any Vault Sidecar Injector annotation
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	var pod corev1.Pod
+	if err = json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
+		t.Fatalf("Error unmarshalling pod: %s", err)
+	}
+
+	pod.Annotations = nil
+	pod.Labels = nil // no application label: role must come from namespace
+
+	// Namespace without defaults: no injection
+	ar.Request.Namespace = "team-b"
+	ar.Request.Object.Raw, _ = json.Marshal(pod)
+
+	resp := vaultInjector.mutate(gocontext.Background(), ar)
+	assert.True(t, resp.Allowed)
+	assert.Nil(t, resp.Patch)
+
+	// Namespace with defaults: injection using namespace's role and secrets path
+	ar.Request.Namespace = "team-a"
+
+	resp = vaultInjector.mutate(gocontext.Background(), ar)
+	assert.True(t, resp.Allowed)
+	assert.Contains(t, string(resp.Patch), "team-a")
+	assert.Contains(t, string(resp.Patch), "secret/team-a/common")
+
+	// Pod's own annotations take precedence
+	pod.Annotations = map[string]string{"sidecar.vault.talend.org/" + ctx.VaultInjectorAnnotationInjectKey: "false"}
+	ar.Request.Object.Raw, _ = json.Marshal(pod)
+
+	resp = vaultInjector.mutate(gocontext.Background(), ar)
+	assert.True(t, resp.Allowed)
+	assert.Nil(t, resp.Patch)
+}
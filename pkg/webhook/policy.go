@@ -0,0 +1,69 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	cfg "talend/vault-sidecar-injector/pkg/config"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	m "talend/vault-sidecar-injector/pkg/mode"
+	"talend/vault-sidecar-injector/pkg/mode/pki"
+	"talend/vault-sidecar-injector/pkg/mode/secrets"
+	"talend/vault-sidecar-injector/pkg/policy"
+	"talend/vault-sidecar-injector/pkg/tracing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Check submitted pod (annotations including namespace defaults) and computed injection context against admission policy, if any
+func checkPolicy(goctx gocontext.Context, config *cfg.VSIConfig, pod *corev1.Pod, podNamespace string, context *ctx.InjectionContext) (err error) {
+	if config.Policy == nil {
+		return nil
+	}
+
+	_, span := tracing.Start(goctx, "checkPolicy")
+	defer func() { tracing.End(span, err) }()
+
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	customTemplate := secrets.IsSecretsCustomTemplate(context)
+
+	return config.Policy.Evaluate(&policy.Input{
+		Namespace:      podNamespace,
+		ServiceAccount: serviceAccount,
+		Labels:         pod.Labels,
+		Annotations:    pod.Annotations,
+		Role:           context.VaultRole,
+		AuthMethod:     context.VaultAuthMethod,
+		SecretsPaths:   getVaultPaths(context),
+		Modes:          m.GetEnabledModes(context.ModesStatus),
+		CustomTemplate: customTemplate,
+		UnknownPaths:   customTemplate || context.ModesStatus[m.VaultInjectorModeProxy], // proxy lets the pod read any path its role allows
+	})
+}
+
+// Vault paths requested by enabled modes: secrets paths and path issuing PKI certificate
+func getVaultPaths(context *ctx.InjectionContext) []string {
+	vaultPaths := secrets.GetSecretsPaths(context)
+
+	if issuePath := pki.GetIssuePath(context); issuePath != "" {
+		vaultPaths = append(vaultPaths, issuePath)
+	}
+
+	return vaultPaths
+}
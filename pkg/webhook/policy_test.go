@@ -0,0 +1,128 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"talend/vault-sidecar-injector/pkg/policy"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutatePolicy(t *testing.T) {
+	appSecretsRule := policy.Rule{Name: "app-secrets", Expression: `secretsPaths.all(path, path.startsWith("secret/" + labels["com.talend.application"] + "/"))`}
+
+	tables := []struct {
+		name              string
+		manifest          string
+		rule              policy.Rule
+		allowUnknownPaths bool
+		allowed           bool
+		message           string
+	}{
+		{
+			"secrets paths allowed",
+			"../../test/workloads/ok/test-app-dep-1.yaml",
+			appSecretsRule,
+			false,
+			true,
+			"",
+		},
+		{
+			"role denied",
+			"../../test/workloads/ok/test-app-dep-1.yaml",
+			policy.Rule{Name: "forbidden-role", Expression: `!(podNamespace == "default" && role == "test" && authMethod == "kubernetes" && serviceAccount == "default")`},
+			false,
+			false,
+			"'forbidden-role'",
+		},
+		{
+			"pki issue path denied",
+			"../../test/workloads/ok/test-app-dep-15.yaml",
+			appSecretsRule,
+			false,
+			false,
+			"'app-secrets'",
+		},
+		{
+			"pki issue path allowed",
+			"../../test/workloads/ok/test-app-dep-15.yaml",
+			policy.Rule{Name: "app-pki", Expression: `secretsPaths.exists(path, path == "pki/issue/" + labels["com.talend.application"]) && secretsPaths.all(path, path.startsWith("secret/" + labels["com.talend.application"] + "/") || path == "pki/issue/" + labels["com.talend.application"])`},
+			false,
+			true,
+			"",
+		},
+		{
+			"custom template denied as paths are unknown",
+			"../../test/workloads/ok/test-app-dep-2.yaml",
+			appSecretsRule,
+			false,
+			false,
+			"Vault paths cannot all be determined",
+		},
+		{
+			"proxy mode denied as paths are unknown",
+			"../../test/workloads/ok/test-app-dep-6.yaml",
+			appSecretsRule,
+			false,
+			false,
+			"Vault paths cannot all be determined",
+		},
+		{
+			"custom template denied by path rule",
+			"../../test/workloads/ok/test-app-dep-2.yaml",
+			policy.Rule{Name: "known-app-secrets", Expression: `!customTemplate && !("proxy" in modes) && ` + appSecretsRule.Expression},
+			true,
+			false,
+			"'known-app-secrets'",
+		},
+		{
+			"custom template allowed by role rule",
+			"../../test/workloads/ok/test-app-dep-2.yaml",
+			policy.Rule{Name: "app-role", Expression: `customTemplate && modes == ["secrets"] && role == labels["com.talend.application"]`},
+			true,
+			true,
+			"",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			vaultInjector, err := createTestVaultInjector()
+			if err != nil {
+				t.Fatalf("Loading error: %s", err)
+			}
+
+			config := vaultInjector.Config()
+			config.Policy = &policy.Policy{Rules: []policy.Rule{table.rule}, AllowUnknownPaths: table.allowUnknownPaths}
+			if err = config.Policy.Compile(); err != nil {
+				t.Fatalf("Compile error: %s", err)
+			}
+
+			ar, err := (&testResource{manifest: table.manifest}).load()
+			if err != nil {
+				t.Fatalf("Error creating AR: %s", err)
+			}
+
+			resp := vaultInjector.mutate(gocontext.Background(), ar)
+			assert.Equal(t, table.allowed, resp.Allowed)
+			if !table.allowed {
+				assert.Nil(t, resp.Patch)
+				assert.Contains(t, resp.Result.Message, table.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"strconv"
+	cfg "talend/vault-sidecar-injector/pkg/config"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"talend/vault-sidecar-injector/pkg/mode/secrets"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog"
+)
+
+// Return a copy of the pod without the containers and volumes injected by a previous invocation (if any), and whether some were found.
+//
+// A 'secrets' volume is only considered as injected when it matches the injector's definition: pod's own one is kept as we never replace it.
+func removeInjected(config *cfg.VSIConfig, pod *corev1.Pod) (*corev1.Pod, bool) {
+	injectedNames := make(map[string]bool)
+	for _, cnt := range config.InjectionConfig.InitContainers {
+		injectedNames[ctx.JsonPathInitContainers+"/"+cnt.Name] = true
+	}
+
+	for _, cnt := range config.InjectionConfig.Containers {
+		injectedNames[ctx.JsonPathContainers+"/"+cnt.Name] = true
+	}
+
+	var secretsVol *corev1.Volume
+	for idx, vol := range config.InjectionConfig.Volumes {
+		if vol.Name == secrets.SecretsVolName {
+			secretsVol = &config.InjectionConfig.Volumes[idx]
+		} else {
+			injectedNames[ctx.JsonPathVolumes+"/"+vol.Name] = true
+		}
+	}
+
+	found := false
+	cleanedPod := pod.DeepCopy()
+
+	removeContainers := func(basePath string, podContainers []corev1.Container) []corev1.Container {
+		var kept []corev1.Container
+		for _, cnt := range podContainers {
+			if injectedNames[basePath+"/"+cnt.Name] {
+				klog.Infof("Found previously injected container '%s' (path: %s)", cnt.Name, basePath)
+				found = true
+				continue
+			}
+
+			kept = append(kept, cnt)
+		}
+
+		return kept
+	}
+
+	cleanedPod.Spec.InitContainers = removeContainers(ctx.JsonPathInitContainers, pod.Spec.InitContainers)
+	cleanedPod.Spec.Containers = removeContainers(ctx.JsonPathContainers, pod.Spec.Containers)
+
+	cleanedPod.Spec.Volumes = nil
+	for _, vol := range pod.Spec.Volumes {
+		if injectedNames[ctx.JsonPathVolumes+"/"+vol.Name] || (secretsVol != nil && equality.Semantic.DeepEqual(vol, *secretsVol)) {
+			klog.Infof("Found previously injected volume '%s'", vol.Name)
+			found = true
+			continue
+		}
+
+		cleanedPod.Spec.Volumes = append(cleanedPod.Spec.Volumes, vol)
+	}
+
+	return cleanedPod, found
+}
+
+// Apply patch computed on cleaned pod and return operations turning submitted pod into the result: only stale or missing
+// containers and volumes are then reconciled.
+func reconcilePodSpec(pod, cleanedPod *corev1.Pod, patch []ctx.PatchOperation) ([]ctx.PatchOperation, error) {
+	rawCleanedPod, err := json.Marshal(cleanedPod)
+	if err != nil {
+		return nil, err
+	}
+
+	rawPatch, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedPatch, err := jsonpatch.DecodePatch(rawPatch)
+	if err != nil {
+		klog.Errorf("Failed to decode JSON Patch: %v", err)
+		return nil, err
+	}
+
+	rawDesiredPod, err := decodedPatch.Apply(rawCleanedPod)
+	if err != nil {
+		klog.Errorf("Failed to apply JSON Patch: %v", err)
+		return nil, err
+	}
+
+	var desiredPod corev1.Pod
+	if err = json.Unmarshal(rawDesiredPod, &desiredPod); err != nil {
+		return nil, err
+	}
+
+	var reconcilePatch []ctx.PatchOperation
+	reconcilePatch = append(reconcilePatch, reconcileField(ctx.JsonPathInitContainers, containerItems(pod.Spec.InitContainers), containerItems(cleanedPod.Spec.InitContainers), containerItems(desiredPod.Spec.InitContainers))...)
+	reconcilePatch = append(reconcilePatch, reconcileField(ctx.JsonPathContainers, containerItems(pod.Spec.Containers), containerItems(cleanedPod.Spec.Containers), containerItems(desiredPod.Spec.Containers))...)
+	reconcilePatch = append(reconcilePatch, reconcileField(ctx.JsonPathVolumes, volumeItems(pod.Spec.Volumes), volumeItems(cleanedPod.Spec.Volumes), volumeItems(desiredPod.Spec.Volumes))...)
+
+	return reconcilePatch, nil
+}
+
+// Named item of a pod's array field (container or volume)
+type namedItem struct {
+	name  string
+	value interface{}
+}
+
+func containerItems(containers []corev1.Container) []namedItem {
+	items := make([]namedItem, 0, len(containers))
+	for _, cnt := range containers {
+		items = append(items, namedItem{name: cnt.Name, value: cnt})
+	}
+
+	return items
+}
+
+func volumeItems(volumes []corev1.Volume) []namedItem {
+	items := make([]namedItem, 0, len(volumes))
+	for _, vol := range volumes {
+		items = append(items, namedItem{name: vol.Name, value: vol})
+	}
+
+	return items
+}
+
+// Return operations turning current items into desired ones, item per item (matched by name):
+//   - items no longer desired are removed
+//   - pod's own items (the ones kept in cleaned pod) are replaced if mutation changed them
+//   - previously injected items still desired are left untouched (they may have been defaulted by the API server since)
+//   - missing items are added, right after the item preceding them in desired ones
+func reconcileField(path string, current, cleaned, desired []namedItem) []ctx.PatchOperation {
+	switch {
+	case len(current) == 0 && len(desired) == 0:
+		return nil
+	case len(desired) == 0:
+		return []ctx.PatchOperation{{Op: ctx.JsonPatchOpRemove, Path: path}}
+	case len(current) == 0:
+		values := make([]interface{}, 0, len(desired))
+		for _, item := range desired {
+			values = append(values, item.value)
+		}
+
+		return []ctx.PatchOperation{{Op: ctx.JsonPatchOpAdd, Path: path, Value: values}}
+	}
+
+	desiredItems := make(map[string]interface{}, len(desired))
+	for _, item := range desired {
+		desiredItems[item.name] = item.value
+	}
+
+	podItems := make(map[string]bool, len(cleaned))
+	for _, item := range cleaned {
+		podItems[item.name] = true
+	}
+
+	var patch []ctx.PatchOperation
+
+	// Remove items no longer desired (starting from the end of the array to keep indexes valid)
+	names := make([]string, len(current))
+	for idx := len(current) - 1; idx >= 0; idx-- {
+		names[idx] = current[idx].name
+		if _, found := desiredItems[current[idx].name]; !found {
+			klog.Infof("Reconciling %s: remove '%s'", path, current[idx].name)
+			patch = append(patch, ctx.PatchOperation{Op: ctx.JsonPatchOpRemove, Path: path + "/" + strconv.Itoa(idx)})
+			names = append(names[:idx], names[idx+1:]...)
+		}
+	}
+
+	currentItems := make(map[string]interface{}, len(current))
+	for _, item := range current {
+		currentItems[item.name] = item.value
+	}
+
+	// Replace pod's own items changed by mutation
+	for idx, name := range names {
+		if podItems[name] && !equality.Semantic.DeepEqual(currentItems[name], desiredItems[name]) {
+			klog.Infof("Reconciling %s: replace '%s'", path, name)
+			patch = append(patch, ctx.PatchOperation{Op: ctx.JsonPatchOpReplace, Path: path + "/" + strconv.Itoa(idx), Value: desiredItems[name]})
+		}
+	}
+
+	// Add missing items
+	insertIdx := 0
+	for _, item := range desired {
+		if idx := indexOf(names, item.name); idx >= 0 {
+			insertIdx = idx + 1
+			continue
+		}
+
+		klog.Infof("Reconciling %s: add '%s'", path, item.name)
+		patch = append(patch, ctx.PatchOperation{Op: ctx.JsonPatchOpAdd, Path: path + "/" + strconv.Itoa(insertIdx), Value: item.value})
+		names = append(names[:insertIdx], append([]string{item.name}, names[insertIdx:]...)...)
+		insertIdx++
+	}
+
+	return patch
+}
+
+func indexOf(names []string, name string) int {
+	for idx := range names {
+		if names[idx] == name {
+			return idx
+		}
+	}
+
+	return -1
+}
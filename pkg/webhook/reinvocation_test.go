@@ -0,0 +1,204 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"path/filepath"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMutateIdempotent(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	workloads, err := filepath.Glob("../../test/workloads/ok/*.yaml")
+	if err != nil {
+		t.Fatalf("Fail listing files: %s", err)
+	}
+
+	for _, workloadManifest := range workloads {
+		ar, err := (&testResource{manifest: workloadManifest}).load()
+		if err != nil {
+			t.Fatalf("Error creating AR: %s", err)
+		}
+
+		var pod corev1.Pod
+		if err = json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
+			t.Fatalf("Error unmarshalling pod: %s", err)
+		}
+
+		pod.Namespace = ar.Request.Namespace
+
+		_, injectedPod, err := vaultInjector.Render(&pod)
+		if !assert.NoError(t, err, workloadManifest) {
+			continue
+		}
+
+		// Re-invocation on injected pod must not change it
+		_, reinjectedPod, err := vaultInjector.Render(injectedPod.DeepCopy())
+		if assert.NoError(t, err, workloadManifest) {
+			assert.Equal(t, injectedPod.Spec, reinjectedPod.Spec, workloadManifest)
+			assert.Equal(t, injectedPod.Annotations, reinjectedPod.Annotations, workloadManifest)
+		}
+	}
+}
+
+func TestMutateReinvokedOnDefaultedPod(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	workloads, err := filepath.Glob("../../test/workloads/ok/*.yaml")
+	if err != nil {
+		t.Fatalf("Fail listing files: %s", err)
+	}
+
+	for _, workloadManifest := range workloads {
+		ar, err := (&testResource{manifest: workloadManifest}).load()
+		if err != nil {
+			t.Fatalf("Error creating AR: %s", err)
+		}
+
+		var pod corev1.Pod
+		if err = json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
+			t.Fatalf("Error unmarshalling pod: %s", err)
+		}
+
+		pod.Namespace = ar.Request.Namespace
+
+		_, injectedPod, err := vaultInjector.Render(&pod)
+		if !assert.NoError(t, err, workloadManifest) {
+			continue
+		}
+
+		// API server sets default values on injected containers and volumes before any reinvocation
+		setDefaults(injectedPod)
+
+		if ar.Request.Object.Raw, err = json.Marshal(injectedPod); err != nil {
+			t.Fatalf("Error marshalling pod: %s", err)
+		}
+
+		resp := vaultInjector.mutate(gocontext.Background(), ar)
+		if assert.True(t, resp.Allowed, workloadManifest) && resp.Patch != nil {
+			var patch []ctx.PatchOperation
+			if assert.NoError(t, json.Unmarshal(resp.Patch, &patch), workloadManifest) {
+				assert.Empty(t, patch, workloadManifest)
+			}
+		}
+	}
+}
+
+func setDefaults(pod *corev1.Pod) {
+	setContainerDefaults := func(containers []corev1.Container) {
+		for idx := range containers {
+			cnt := &containers[idx]
+			if cnt.TerminationMessagePath == "" {
+				cnt.TerminationMessagePath = corev1.TerminationMessagePathDefault
+			}
+			if cnt.TerminationMessagePolicy == "" {
+				cnt.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+			}
+			if cnt.ImagePullPolicy == "" {
+				cnt.ImagePullPolicy = corev1.PullIfNotPresent
+			}
+			if cnt.Resources.Requests == nil {
+				cnt.Resources.Requests = cnt.Resources.Limits
+			}
+		}
+	}
+
+	setContainerDefaults(pod.Spec.InitContainers)
+	setContainerDefaults(pod.Spec.Containers)
+
+	defaultMode := corev1.SecretVolumeSourceDefaultMode
+	for idx := range pod.Spec.Volumes {
+		vol := &pod.Spec.Volumes[idx]
+		switch {
+		case vol.Secret != nil && vol.Secret.DefaultMode == nil:
+			vol.Secret.DefaultMode = &defaultMode
+		case vol.ConfigMap != nil && vol.ConfigMap.DefaultMode == nil:
+			vol.ConfigMap.DefaultMode = &defaultMode
+		case vol.Projected != nil && vol.Projected.DefaultMode == nil:
+			vol.Projected.DefaultMode = &defaultMode
+		}
+	}
+}
+
+func TestMutateStatusCopied(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	var pod corev1.Pod
+	if err = json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
+		t.Fatalf("Error unmarshalling pod: %s", err)
+	}
+
+	// Status annotation copied from a template: injection must be performed anyway
+	statusKey := vaultInjector.Config().VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationStatusKey]
+	pod.Annotations[statusKey] = ctx.VaultInjectorStatusInjected
+
+	_, injectedPod, err := vaultInjector.Render(&pod)
+	if assert.NoError(t, err) {
+		assert.True(t, len(injectedPod.Spec.Containers) > len(pod.Spec.Containers))
+		assert.Equal(t, ctx.VaultInjectorStatusInjected, injectedPod.Annotations[statusKey])
+
+		// Pod's own annotations are kept
+		for key, value := range pod.Annotations {
+			assert.Equal(t, value, injectedPod.Annotations[key])
+		}
+	}
+}
+
+func TestReconcileField(t *testing.T) {
+	items := func(names ...string) []namedItem {
+		var items []namedItem
+		for _, name := range names {
+			items = append(items, namedItem{name: name, value: corev1.Volume{Name: name}})
+		}
+		return items
+	}
+
+	// Stale injected item removed, missing one added at its place, pod's own items untouched
+	patch := reconcileField(ctx.JsonPathVolumes, items("old-injected", "app", "kept-injected"), items("app"), items("new-injected", "app", "kept-injected"))
+	assert.Equal(t, []ctx.PatchOperation{
+		{Op: ctx.JsonPatchOpRemove, Path: ctx.JsonPathVolumes + "/0"},
+		{Op: ctx.JsonPatchOpAdd, Path: ctx.JsonPathVolumes + "/0", Value: corev1.Volume{Name: "new-injected"}},
+	}, patch)
+
+	// Pod's own item changed by mutation is replaced
+	current := items("app")
+	desired := []namedItem{{name: "app", value: corev1.Volume{Name: "app", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}}
+	patch = reconcileField(ctx.JsonPathVolumes, current, current, desired)
+	assert.Equal(t, []ctx.PatchOperation{{Op: ctx.JsonPatchOpReplace, Path: ctx.JsonPathVolumes + "/0", Value: desired[0].value}}, patch)
+
+	// Nothing left to inject
+	assert.Equal(t, []ctx.PatchOperation{{Op: ctx.JsonPatchOpRemove, Path: ctx.JsonPathVolumes}}, reconcileField(ctx.JsonPathVolumes, items("old-injected"), nil, nil))
+}
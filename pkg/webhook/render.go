@@ -0,0 +1,127 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog"
+)
+
+// Render submits pod to the mutation process (outside of any admission flow) and returns computed JSON Patch along with patched pod
+func (vaultInjector *VaultInjector) Render(pod *corev1.Pod) ([]byte, *corev1.Pod, error) {
+	// Service Account Admission Controller, which runs before mutating webhooks, is not there to add the token volume we look after
+	addServiceAccountTokenVolume(pod)
+
+	rawPod, err := json.Marshal(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp := vaultInjector.mutate(gocontext.Background(), &admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			UID: uuid.NewUUID(),
+			Kind: metav1.GroupVersionKind{
+				Version: "v1",
+				Kind:    "Pod",
+			},
+			Namespace: pod.Namespace,
+			Operation: admv1.Create,
+			Object: runtime.RawExtension{
+				Raw: rawPod,
+			},
+		},
+	})
+
+	if !resp.Allowed {
+		if resp.Result != nil {
+			return nil, nil, errors.New(resp.Result.Message)
+		}
+		return nil, nil, errors.New("pod denied")
+	}
+
+	if resp.Patch == nil { // No mutation
+		return nil, pod, nil
+	}
+
+	patch, err := jsonpatch.DecodePatch(resp.Patch)
+	if err != nil {
+		klog.Errorf("Failed to decode JSON Patch: %v", err)
+		return nil, nil, err
+	}
+
+	rawPatchedPod, err := patch.Apply(rawPod)
+	if err != nil {
+		klog.Errorf("Failed to apply JSON Patch: %v", err)
+		return nil, nil, err
+	}
+
+	var patchedPod corev1.Pod
+	if err := json.Unmarshal(rawPatchedPod, &patchedPod); err != nil {
+		return nil, nil, err
+	}
+
+	return resp.Patch, &patchedPod, nil
+}
+
+func addServiceAccountTokenVolume(pod *corev1.Pod) {
+	if len(pod.Spec.Containers) == 0 {
+		return
+	}
+
+	if pod.Spec.AutomountServiceAccountToken != nil && !*pod.Spec.AutomountServiceAccountToken {
+		return
+	}
+
+	for _, cnt := range pod.Spec.Containers {
+		for _, volMount := range cnt.VolumeMounts {
+			if volMount.MountPath == k8sDefaultSATokenVolMountPath {
+				return // Already there
+			}
+		}
+	}
+
+	serviceAccountName := pod.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+
+	saTokenVolumeName := serviceAccountName + "-token"
+
+	for cntIdx := range pod.Spec.Containers {
+		pod.Spec.Containers[cntIdx].VolumeMounts = append(pod.Spec.Containers[cntIdx].VolumeMounts, corev1.VolumeMount{
+			Name:      saTokenVolumeName,
+			ReadOnly:  true,
+			MountPath: k8sDefaultSATokenVolMountPath,
+		})
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: saTokenVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: saTokenVolumeName,
+			},
+		},
+	})
+}
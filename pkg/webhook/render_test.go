@@ -0,0 +1,215 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	cfg "talend/vault-sidecar-injector/pkg/config"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRender(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	tables := []struct {
+		name               string
+		annotations        map[string]string
+		injectedContainers []string
+	}{
+		{
+			name:               "No injection",
+			annotations:        map[string]string{},
+			injectedContainers: nil,
+		},
+		{
+			name:               "Secrets mode",
+			annotations:        map[string]string{"sidecar.vault.talend.org/inject": "true"},
+			injectedContainers: []string{cfg.VaultAgentContainerName},
+		},
+		{
+			name:               "Job mode",
+			annotations:        map[string]string{"sidecar.vault.talend.org/inject": "true", "sidecar.vault.talend.org/mode": "job"},
+			injectedContainers: []string{cfg.JobMonitoringContainerName, cfg.VaultAgentContainerName},
+		},
+		{
+			name:               "PKI mode",
+			annotations:        map[string]string{"sidecar.vault.talend.org/inject": "true", "sidecar.vault.talend.org/mode": "pki"},
+			injectedContainers: []string{cfg.VaultAgentContainerName},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			// No service account token volume: expect Render to add it
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-app-",
+					Namespace:    "default",
+					Annotations:  table.annotations,
+					Labels: map[string]string{
+						"com.talend.application": "test",
+						"com.talend.service":     "test-app-svc",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "testcontainer", Image: "myfakeimage:1.0.0"}},
+				},
+			}
+
+			patch, patchedPod, err := vaultInjector.Render(pod)
+			if err != nil {
+				t.Fatalf("Render error: %s", err)
+			}
+
+			if table.injectedContainers == nil {
+				assert.Nil(t, patch)
+				assert.Len(t, patchedPod.Spec.Containers, 1)
+				return
+			}
+
+			assert.NotNil(t, patch)
+			assert.Equal(t, ctx.VaultInjectorStatusInjected, patchedPod.Annotations["sidecar.vault.talend.org/status"])
+
+			var containerNames []string
+			for _, cnt := range patchedPod.Spec.Containers {
+				containerNames = append(containerNames, cnt.Name)
+			}
+
+			assert.ElementsMatch(t, append(table.injectedContainers, "testcontainer"), containerNames)
+		})
+	}
+}
+
+func TestRenderPKI(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	tables := []struct {
+		name        string
+		annotations map[string]string
+		labels      map[string]string
+		expected    []string
+		unexpected  []string
+		err         bool
+	}{
+		{
+			name:   "Default names",
+			labels: map[string]string{"com.talend.application": "test", "com.talend.service": "test-app-svc"},
+			expected: []string{
+				`destination = "/opt/talend/secrets/cert.pem"`,
+				`destination = "/opt/talend/secrets/key.pem"`,
+				`destination = "/opt/talend/secrets/ca.pem"`,
+				`{{ with secret "pki/issue/test" (printf "common_name=%[1]s.%[2]s.svc" "test-app-svc" (env "POD_NAMESPACE")) (printf "alt_names=%[1]s,%[2]s,%[2]s.%[3]s,%[2]s.%[3]s.svc" (env "POD_NAME") "test-app-svc" (env "POD_NAMESPACE")) }}{{ .Data.certificate }}{{ end }}`,
+				`{{ .Data.private_key }}`,
+				`{{ .Data.issuing_ca }}`,
+			},
+			unexpected: []string{"ttl="},
+		},
+		{
+			name: "Custom settings",
+			annotations: map[string]string{
+				"sidecar.vault.talend.org/pki-mount":       "/pki_int/",
+				"sidecar.vault.talend.org/pki-role":        "web",
+				"sidecar.vault.talend.org/pki-common-name": "www.example.com",
+				"sidecar.vault.talend.org/pki-alt-names":   "example.com, api.example.com",
+				"sidecar.vault.talend.org/pki-ttl":         "72h",
+				"sidecar.vault.talend.org/pki-notify":      "pkill -HUP nginx",
+			},
+			labels: map[string]string{"com.talend.application": "test"},
+			expected: []string{
+				`{{ with secret "pki_int/issue/web" "common_name=www.example.com" "alt_names=example.com,api.example.com" "ttl=72h" }}{{ .Data.certificate }}{{ end }}`,
+				`command = "pkill -HUP nginx"`,
+			},
+		},
+		{
+			name:        "Missing service label",
+			annotations: map[string]string{"sidecar.vault.talend.org/pki-common-name": "www.example.com"},
+			labels:      map[string]string{"com.talend.application": "test"},
+			err:         true,
+		},
+		{
+			name:        "Missing PKI role",
+			annotations: map[string]string{"sidecar.vault.talend.org/role": "test"},
+			labels:      map[string]string{"com.talend.service": "test-app-svc"},
+			err:         true,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			annotations := map[string]string{"sidecar.vault.talend.org/inject": "true", "sidecar.vault.talend.org/mode": "pki"}
+			for key, value := range table.annotations {
+				annotations[key] = value
+			}
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-app-",
+					Namespace:    "default",
+					Annotations:  annotations,
+					Labels:       table.labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "testcontainer", Image: "myfakeimage:1.0.0"}},
+				},
+			}
+
+			_, patchedPod, err := vaultInjector.Render(pod)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Render error: %s", err)
+			}
+
+			var templates string
+			for _, cnt := range patchedPod.Spec.Containers {
+				if cnt.Name == cfg.VaultAgentContainerName {
+					for _, env := range cnt.Env {
+						if env.Name == "VSI_PKI_TEMPLATES_PLACEHOLDER" {
+							templates = env.Value
+						}
+					}
+				}
+			}
+
+			for _, expected := range table.expected {
+				assert.Contains(t, templates, expected)
+			}
+
+			for _, unexpected := range table.unexpected {
+				assert.NotContains(t, templates, unexpected)
+			}
+
+			// Notify command only set on certificate's template so that it runs once per renewal
+			if table.annotations["sidecar.vault.talend.org/pki-notify"] != "" {
+				assert.Equal(t, 2, strings.Count(templates, `command = ""`))
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// Shutdown : stop webhook server gracefully. Replica is first marked unready, then we wait for grace delay (so that endpoints
+// get updated and API server stops sending requests) before draining in-flight admission requests until drain timeout expires.
+func (vaultInjector *VaultInjector) Shutdown(graceDelay, drainTimeout time.Duration) error {
+	vaultInjector.SetShuttingDown()
+
+	if graceDelay > 0 {
+		klog.Infof("Replica marked unready, waiting %v before draining webhook server (%d admission requests in flight)", graceDelay, vaultInjector.InFlightRequests())
+		time.Sleep(graceDelay)
+	}
+
+	goctx := gocontext.Background()
+	if drainTimeout > 0 {
+		var cancel gocontext.CancelFunc
+		goctx, cancel = gocontext.WithTimeout(goctx, drainTimeout)
+		defer cancel()
+	}
+
+	klog.Infof("Draining webhook server (%d admission requests in flight, timeout: %v)", vaultInjector.InFlightRequests(), drainTimeout)
+	if err := vaultInjector.Server.Shutdown(goctx); err != nil {
+		klog.Errorf("Webhook server not drained: %v (%d admission requests still in flight)", err, vaultInjector.InFlightRequests())
+		return err
+	}
+
+	klog.Info("Webhook server drained")
+	return nil
+}
+
+// InFlightRequests : number of admission requests currently processed
+func (vaultInjector *VaultInjector) InFlightRequests() int32 {
+	return atomic.LoadInt32(&vaultInjector.inFlight)
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	tables := []struct {
+		name         string
+		stuckRequest bool
+		drained      bool
+	}{
+		{"no request in flight", false, true},
+		{"request still in flight", true, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			vaultInjector, err := createTestVaultInjector()
+			if err != nil {
+				t.Fatalf("Loading error: %s", err)
+			}
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen error: %s", err)
+			}
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+
+			vaultInjector.Server = &http.Server{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(started)
+					<-release
+				}),
+			}
+			go vaultInjector.Server.Serve(listener)
+
+			if table.stuckRequest {
+				go http.Get("http://" + listener.Addr().String())
+				<-started
+			}
+
+			done := make(chan error)
+			go func() { done <- vaultInjector.Shutdown(50*time.Millisecond, 100*time.Millisecond) }()
+
+			// Replica is marked unready before server is drained
+			time.Sleep(10 * time.Millisecond)
+			assert.Error(t, vaultInjector.ready())
+
+			err = <-done
+			if table.drained {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, gocontext.DeadlineExceeded)
+			}
+		})
+	}
+}
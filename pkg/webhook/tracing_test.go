@@ -0,0 +1,65 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"talend/vault-sidecar-injector/pkg/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousTracerProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracerProvider)
+	defer otel.SetTracerProvider(previousTracerProvider)
+
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	resp := vaultInjector.mutate(gocontext.Background(), ar)
+	assert.True(t, resp.Allowed)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+
+		// All spans must identify the admission request
+		assert.Contains(t, span.Attributes, attribute.String(tracing.AttributeUID, string(ar.Request.UID)), "span %s", span.Name)
+		assert.Contains(t, span.Attributes, attribute.String(tracing.AttributeNamespace, "default"), "span %s", span.Name)
+	}
+
+	for _, name := range []string{"computeContext", "secrets.ComputeTemplatesFunc", "secrets.InjectContainerFunc", "marshalPatch"} {
+		assert.Contains(t, spans, name)
+	}
+
+	// Modes' compute functions are called within computeContext
+	assert.Equal(t, spans["computeContext"].SpanContext.SpanID(), spans["secrets.ComputeTemplatesFunc"].Parent.SpanID())
+}
@@ -15,17 +15,35 @@
 package webhook
 
 import (
+	gocontext "context"
 	"net/http"
-	cfg "talend/vault-sidecar-injector/pkg/config"
+	"sync/atomic"
+	"talend/vault-sidecar-injector/pkg/audit"
 	ctx "talend/vault-sidecar-injector/pkg/context"
+	"time"
+
+	admv1 "k8s.io/api/admission/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 // VaultInjector : Webhook Server entity
 type VaultInjector struct {
-	*cfg.VSIConfig
-	Server *http.Server
+	config              atomic.Value // *cfg.VSIConfig in use
+	Server              *http.Server
+	CertExpiryThreshold time.Duration               // readiness fails when webhook certificate expires within this duration
+	AuditLogger         *audit.Logger               // audit records of admission decisions (disabled if nil)
+	EventRecorder       record.EventRecorder        // Kubernetes Events emitted on denied injection (disabled if nil)
+	NamespaceLister     corelisters.NamespaceLister // namespaces providing default annotations (disabled if nil)
+	MaxRequestBodySize  int64                       // max size in bytes of admission requests' body (no limit if 0)
+	requestSlots        chan struct{}               // limits number of concurrent admission requests (no limit if nil)
+	inFlight            int32                       // number of admission requests currently processed
+	shuttingDown        int32                       // set to 1 when server is shutting down
 }
 
+// admitFunc : process an admission request and return the response (mutation or validation)
+type admitFunc func(goctx gocontext.Context, ar *admv1.AdmissionReview) *admv1.AdmissionResponse
+
 // Supported annotations (modes' annotations will be appended to this array)
 var vaultInjectorAnnotationKeys = []string{
 	ctx.VaultInjectorAnnotationInjectKey,
@@ -35,6 +53,7 @@ var vaultInjectorAnnotationKeys = []string{
 	ctx.VaultInjectorAnnotationRoleKey,
 	ctx.VaultInjectorAnnotationSATokenKey,
 	ctx.VaultInjectorAnnotationWorkloadKey,
+	ctx.VaultInjectorAnnotationSkipKey,
 	ctx.VaultInjectorAnnotationStatusKey,
 }
 
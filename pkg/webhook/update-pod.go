@@ -15,51 +15,52 @@
 package webhook
 
 import (
-	"errors"
+	gocontext "context"
 	"fmt"
 	"strconv"
 	"strings"
 
-	"talend/vault-sidecar-injector/pkg/config"
+	cfg "talend/vault-sidecar-injector/pkg/config"
 	ctx "talend/vault-sidecar-injector/pkg/context"
 	m "talend/vault-sidecar-injector/pkg/mode"
 	"talend/vault-sidecar-injector/pkg/mode/secrets"
+	"talend/vault-sidecar-injector/pkg/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
 )
 
-func (vaultInjector *VaultInjector) updatePodSpec(pod *corev1.Pod) (patch []ctx.PatchOperation, err error) {
-	var context *ctx.InjectionContext
+func (vaultInjector *VaultInjector) updatePodSpec(goctx gocontext.Context, config *cfg.VSIConfig, pod *corev1.Pod) (patch []ctx.PatchOperation, context *ctx.InjectionContext, err error) {
 	var patchPod, patchInitContainers, patchContainers []ctx.PatchOperation
 
 	// We expect at least one container in submitted pod
 	if len(pod.Spec.Containers) == 0 {
-		err = errors.New("Submitted pod must contain at least one container")
+		err = ctx.NewInjectionError(ctx.ReasonNoContainer, "Submitted pod must contain at least one container")
 		klog.Error(err.Error())
 		return
 	}
 
 	// 1) Extract labels and annotations to compute values for placeholders in injection configuration
-	if context, err = vaultInjector.computeContext(pod.Spec.Containers, pod.Labels, pod.Annotations); err == nil {
+	if context, err = vaultInjector.computeContext(goctx, config, pod.Spec.Containers, pod.Labels, pod.Annotations); err == nil {
 		if klog.V(5) { // enabled by providing '-v=5' at least
 			klog.Infof("context=%+v", context)
 		}
 
 		// 2) Patch submitted pod
-		if patchPod, err = vaultInjector.patchPod(pod.Spec, pod.Annotations, context); err == nil {
+		if patchPod, err = vaultInjector.patchPod(goctx, config, pod.Spec, pod.Annotations, context); err == nil {
 			patch = append(patch, patchPod...)
 
 			// 3) If needed, add volumeMounts and volumes to submitted pod.
 			// Do it *before* injecting new init container(s)/container(s) because container index will then change (index used when adding volumeMounts).
-			patch = append(patch, vaultInjector.addStorage(pod.Spec)...)
+			patch = append(patch, vaultInjector.addStorage(config, pod.Spec)...)
 
 			// 4) Add init container(s) to submitted pod
-			if patchInitContainers, err = vaultInjector.addContainer(pod.Spec.InitContainers, ctx.JsonPathInitContainers, context); err == nil {
+			if patchInitContainers, err = vaultInjector.addContainer(goctx, config, pod.Spec.InitContainers, ctx.JsonPathInitContainers, context); err == nil {
 				patch = append(patch, patchInitContainers...)
 
 				// 5) Add sidecar(s) to submitted pod
-				if patchContainers, err = vaultInjector.addContainer(pod.Spec.Containers, ctx.JsonPathContainers, context); err == nil {
+				if patchContainers, err = vaultInjector.addContainer(goctx, config, pod.Spec.Containers, ctx.JsonPathContainers, context); err == nil {
 					patch = append(patch, patchContainers...)
 				}
 			}
@@ -69,25 +70,30 @@ func (vaultInjector *VaultInjector) updatePodSpec(pod *corev1.Pod) (patch []ctx.
 	return
 }
 
-func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Container, labels, annotations map[string]string) (*ctx.InjectionContext, error) {
+func (vaultInjector *VaultInjector) computeContext(goctx gocontext.Context, config *cfg.VSIConfig, podContainers []corev1.Container, labels, annotations map[string]string) (injectionContext *ctx.InjectionContext, err error) {
 	var k8sSaSecretsVolName, vaultInjectorSaSecretsVolName string
 
+	goctx, span := tracing.Start(goctx, "computeContext")
+	defer func() { tracing.End(span, err) }()
+
 	// Get status for Vault Sidecar Injector modes
 	modesStatus := make(map[string]bool, len(m.VaultInjectorModes))
-	m.GetModesStatus(strings.Split(annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationModeKey]], ","), modesStatus)
+	warnings := m.GetModesStatus(strings.Split(annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationModeKey]], ","), modesStatus)
 
 	// !!! This annotation is deprecated !!! Enable job mode if used
-	if annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationWorkloadKey]] == m.VaultInjectorModeJob {
-		klog.Warningf("Annotation '%s' is deprecated but still supported. Use '%s' instead", vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationWorkloadKey], vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationModeKey])
+	if annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationWorkloadKey]] == m.VaultInjectorModeJob {
+		warning := fmt.Sprintf("Annotation '%s' is deprecated but still supported. Use '%s' instead", config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationWorkloadKey], config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationModeKey])
+		klog.Warning(warning)
+		warnings = append(warnings, warning)
 		modesStatus[m.VaultInjectorModeJob] = true
 	}
 
 	klog.Infof("Modes status: %+v", modesStatus)
 
-	vaultImage := annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationVaultImageKey]]
-	vaultAuthMethod := strings.ToLower(annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationAuthMethodKey]])
-	vaultRole := annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationRoleKey]]
-	vaultSATokenPath := annotations[vaultInjector.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationSATokenKey]]
+	vaultImage := annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationVaultImageKey]]
+	vaultAuthMethod := strings.ToLower(annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationAuthMethodKey]])
+	vaultRole := annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationRoleKey]]
+	vaultSATokenPath := annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationSATokenKey]]
 
 	if vaultAuthMethod == "" { // Default Vault Auth Method is "kubernetes"
 		vaultAuthMethod = ctx.VaultK8sAuthMethod
@@ -101,7 +107,7 @@ func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Contai
 		}
 
 		if !vaultAuthMethodSupported {
-			err := fmt.Errorf("Submitted pod makes use of unsupported Vault Auth Method '%s'", vaultAuthMethod)
+			err := ctx.NewInjectionError(ctx.ReasonUnsupportedAuthMethod, "Submitted pod makes use of unsupported Vault Auth Method '%s'", vaultAuthMethod)
 			klog.Errorf(err.Error())
 			return nil, err
 		}
@@ -109,10 +115,10 @@ func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Contai
 
 	if (vaultRole == "") && (vaultAuthMethod == ctx.VaultK8sAuthMethod) { // If role annotation not provided and "kubernetes" Vault Auth
 		// Look after application label to set role
-		vaultRole = labels[vaultInjector.ApplicationLabelKey]
+		vaultRole = labels[config.ApplicationLabelKey]
 
 		if vaultRole == "" {
-			err := fmt.Errorf("Submitted pod must contain label %s", vaultInjector.ApplicationLabelKey)
+			err := ctx.NewInjectionError(ctx.ReasonMissingLabel, "Submitted pod must contain label %s", config.ApplicationLabelKey)
 			klog.Error(err.Error())
 			return nil, err
 		}
@@ -122,7 +128,7 @@ func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Contai
 	// possible custom value provided with 'sa-token' annotation (get rid of ending '/token' if any to have mount path only).
 	//
 	// To be done since Service Account Admission Controller does not automatically add volumeSource for our injected containers.
-	k8sSaSecretsVolName, err := getServiceAccountTokenVolumeName(podContainers, k8sDefaultSATokenVolMountPath)
+	k8sSaSecretsVolName, err = getServiceAccountTokenVolumeName(podContainers, k8sDefaultSATokenVolMountPath)
 	if err != nil {
 		return nil, err
 	}
@@ -140,8 +146,14 @@ func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Contai
 	modesConfig := make(map[string]ctx.ModeConfig, len(m.VaultInjectorModes))
 
 	for mode, enabled := range modesStatus {
+		if err = checkDeadline(goctx); err != nil {
+			return nil, err
+		}
+
 		if enabled && m.VaultInjectorModes[mode].ComputeTemplatesFunc != nil {
-			modesConfig[mode], err = m.VaultInjectorModes[mode].ComputeTemplatesFunc(vaultInjector.VSIConfig, labels, annotations)
+			_, modeSpan := tracing.Start(goctx, mode+".ComputeTemplatesFunc")
+			modesConfig[mode], err = m.VaultInjectorModes[mode].ComputeTemplatesFunc(config, labels, annotations)
+			tracing.End(modeSpan, err)
 			if err != nil {
 				return nil, err
 			}
@@ -155,13 +167,16 @@ func (vaultInjector *VaultInjector) computeContext(podContainers []corev1.Contai
 		VaultAuthMethod:                vaultAuthMethod,
 		VaultRole:                      vaultRole,
 		ModesStatus:                    modesStatus,
-		ModesConfig:                    modesConfig}, nil
+		ModesConfig:                    modesConfig,
+		Warnings:                       warnings}, nil
 }
 
-func (vaultInjector *VaultInjector) patchPod(podSpec corev1.PodSpec, annotations map[string]string, context *ctx.InjectionContext) (patch []ctx.PatchOperation, err error) {
+func (vaultInjector *VaultInjector) patchPod(goctx gocontext.Context, config *cfg.VSIConfig, podSpec corev1.PodSpec, annotations map[string]string, context *ctx.InjectionContext) (patch []ctx.PatchOperation, err error) {
 	for mode, enabled := range context.ModesStatus {
 		if enabled && m.VaultInjectorModes[mode].PatchPodFunc != nil {
-			patchPod, err := m.VaultInjectorModes[mode].PatchPodFunc(vaultInjector.VSIConfig, podSpec, annotations, context)
+			_, modeSpan := tracing.Start(goctx, mode+".PatchPodFunc")
+			patchPod, err := m.VaultInjectorModes[mode].PatchPodFunc(config, podSpec, annotations, context)
+			tracing.End(modeSpan, err)
 			if err != nil {
 				return nil, err
 			}
@@ -174,17 +189,17 @@ func (vaultInjector *VaultInjector) patchPod(podSpec corev1.PodSpec, annotations
 }
 
 // Deal with both InitContainers & Containers
-func (vaultInjector *VaultInjector) addContainer(podContainers []corev1.Container, basePath string, context *ctx.InjectionContext) (patch []ctx.PatchOperation, err error) {
+func (vaultInjector *VaultInjector) addContainer(goctx gocontext.Context, config *cfg.VSIConfig, podContainers []corev1.Container, basePath string, context *ctx.InjectionContext) (patch []ctx.PatchOperation, err error) {
 	var value interface{}
 
 	first := false
-	injectionCfgContainers := vaultInjector.InjectionConfig.Containers
+	injectionCfgContainers := config.InjectionConfig.Containers
 	initContainer := (basePath == ctx.JsonPathInitContainers)
 
 	if initContainer {
 		// there may be no init container in the requesting pod
 		first = (len(podContainers) == 0)
-		injectionCfgContainers = vaultInjector.InjectionConfig.InitContainers
+		injectionCfgContainers = config.InjectionConfig.InitContainers
 	}
 
 	// Add our injected containers/initContainers to the submitted pod
@@ -200,7 +215,10 @@ func (vaultInjector *VaultInjector) addContainer(podContainers []corev1.Containe
 		inject := false
 		for mode, enabled := range context.ModesStatus {
 			if enabled {
+				_, modeSpan := tracing.Start(goctx, mode+".InjectContainerFunc")
+				modeSpan.SetAttributes(attribute.String("container", container.Name))
 				modeInject, err := m.VaultInjectorModes[mode].InjectContainerFunc(basePath, podContainers, container.Name, container.Env, context)
+				tracing.End(modeSpan, err)
 				if err != nil {
 					return nil, err
 				}
@@ -247,7 +265,7 @@ func (vaultInjector *VaultInjector) addContainer(podContainers []corev1.Containe
 
 		// Check if custom Vault image is provided and, if so, update image for relevant containers
 		if context.VaultImage != "" {
-			if (container.Name == config.VaultAgentInitContainerName) || (container.Name == config.VaultAgentContainerName) {
+			if (container.Name == cfg.VaultAgentInitContainerName) || (container.Name == cfg.VaultAgentContainerName) {
 				container.Image = context.VaultImage
 			}
 		}
@@ -275,20 +293,21 @@ func (vaultInjector *VaultInjector) addContainer(podContainers []corev1.Containe
 			Value: value,
 		})
 
+		context.InjectedContainers = append(context.InjectedContainers, container.Name)
 		injectionCntIdx++
 	}
 
 	return patch, nil
 }
 
-func (vaultInjector *VaultInjector) addStorage(podSpec corev1.PodSpec) (patch []ctx.PatchOperation) {
-	patch = append(patch, vaultInjector.addVolumeMount(podSpec.InitContainers, ctx.JsonPathInitContainers)...)
-	patch = append(patch, vaultInjector.addVolumeMount(podSpec.Containers, ctx.JsonPathContainers)...)
-	patch = append(patch, vaultInjector.addVolume(podSpec.Volumes)...)
+func (vaultInjector *VaultInjector) addStorage(config *cfg.VSIConfig, podSpec corev1.PodSpec) (patch []ctx.PatchOperation) {
+	patch = append(patch, vaultInjector.addVolumeMount(config, podSpec.InitContainers, ctx.JsonPathInitContainers)...)
+	patch = append(patch, vaultInjector.addVolumeMount(config, podSpec.Containers, ctx.JsonPathContainers)...)
+	patch = append(patch, vaultInjector.addVolume(config, podSpec.Volumes)...)
 	return
 }
 
-func (vaultInjector *VaultInjector) addVolumeMount(podContainers []corev1.Container, basePath string) (patch []ctx.PatchOperation) {
+func (vaultInjector *VaultInjector) addVolumeMount(config *cfg.VSIConfig, podContainers []corev1.Container, basePath string) (patch []ctx.PatchOperation) {
 	var value interface{}
 	initContainer := (basePath == ctx.JsonPathInitContainers)
 
@@ -340,12 +359,12 @@ func (vaultInjector *VaultInjector) addVolumeMount(podContainers []corev1.Contai
 	return
 }
 
-func (vaultInjector *VaultInjector) addVolume(podVolumes []corev1.Volume) (patch []ctx.PatchOperation) {
+func (vaultInjector *VaultInjector) addVolume(config *cfg.VSIConfig, podVolumes []corev1.Volume) (patch []ctx.PatchOperation) {
 	var value interface{}
 	first := len(podVolumes) == 0
 
 	// Here we inject volumes defined in the injection configuration
-	for _, sidecarVol := range vaultInjector.InjectionConfig.Volumes {
+	for _, sidecarVol := range config.InjectionConfig.Volumes {
 		// Do not inject the 'secrets' volume we define in our injector config if the pod we mutate already has a definition for such volume
 		isSecretsVolumeInPod := false
 		if sidecarVol.Name == secrets.SecretsVolName && len(podVolumes) > 0 {
@@ -15,10 +15,11 @@
 package webhook
 
 import (
-	"fmt"
+	"path"
 	"strings"
 
 	ctx "talend/vault-sidecar-injector/pkg/context"
+	m "talend/vault-sidecar-injector/pkg/mode"
 
 	admv1 "k8s.io/api/admission/v1"
 	admv1beta1 "k8s.io/api/admission/v1beta1"
@@ -39,6 +40,11 @@ func init() {
 	// admission v1beta1
 	must(admv1beta1.AddToScheme(runtimeScheme))
 	must(admregv1beta1.AddToScheme(runtimeScheme))
+
+	// Add modes' annotations to supported annotations (modes are registered at this stage, see modes.go)
+	for _, mode := range m.VaultInjectorModes {
+		vaultInjectorAnnotationKeys = append(vaultInjectorAnnotationKeys, mode.Annotations...)
+	}
 }
 
 func must(err error) {
@@ -47,10 +53,9 @@ func must(err error) {
 	}
 }
 
-// Check whether the target resource need to be mutated
-func mutationRequired(ignoredList []string, vaultInjectorAnnotations map[string]string, podMetadata *metav1.ObjectMeta) bool {
+// Check whether the target resource need to be mutated, if not also return the reason
+func mutationRequired(skipList []string, vaultInjectorAnnotations map[string]string, namespace string, podMetadata *metav1.ObjectMeta) (bool, string) {
 	var entityName string
-	var entityNamespace string
 
 	if podMetadata.Name == "" {
 		entityName = podMetadata.GenerateName
@@ -58,17 +63,11 @@ func mutationRequired(ignoredList []string, vaultInjectorAnnotations map[string]
 		entityName = podMetadata.Name
 	}
 
-	if podMetadata.Namespace == "" {
-		entityNamespace = metav1.NamespaceDefault
-	} else {
-		entityNamespace = podMetadata.Namespace
-	}
-
-	// skip special Kubernetes system namespaces
-	for _, namespace := range ignoredList {
-		if entityNamespace == namespace {
-			klog.Infof("Skip mutation for %v for it's in special namespace:%v", entityName, entityNamespace)
-			return false
+	// skip special Kubernetes system namespaces and configured ones
+	for _, skipped := range skipList {
+		if matched, _ := path.Match(skipped, namespace); matched {
+			klog.Infof("Skip mutation for %v for it's in skipped namespace:%v", entityName, namespace)
+			return false, ctx.SkipReasonIgnoredNamespace
 		}
 	}
 
@@ -79,21 +78,30 @@ func mutationRequired(ignoredList []string, vaultInjectorAnnotations map[string]
 
 	status := annotations[vaultInjectorAnnotations[ctx.VaultInjectorAnnotationStatusKey]]
 
-	// determine whether to perform mutation based on annotation for the target resource
+	// determine whether to perform mutation based on annotation (and label for opt-out) for the target resource.
+	// Status annotation is not trusted (it may have been copied from a template, or pod may have been mutated since): mutation being
+	// idempotent, previously injected pods are reconciled.
 	var required bool
-	if strings.ToLower(status) == ctx.VaultInjectorStatusInjected {
-		required = false
+	var reason string
+	if isTrue(annotations[vaultInjectorAnnotations[ctx.VaultInjectorAnnotationSkipKey]]) || isTrue(podMetadata.Labels[vaultInjectorAnnotations[ctx.VaultInjectorAnnotationSkipKey]]) {
+		reason = ctx.SkipReasonOptOut
+	} else if isTrue(annotations[vaultInjectorAnnotations[ctx.VaultInjectorAnnotationInjectKey]]) {
+		required = true
 	} else {
-		switch strings.ToLower(annotations[vaultInjectorAnnotations[ctx.VaultInjectorAnnotationInjectKey]]) {
-		default:
-			required = false
-		case "y", "yes", "true", "on":
-			required = true
-		}
+		reason = ctx.SkipReasonNotRequested
 	}
 
-	klog.Infof("Mutation policy for %v/%v: status: %q required:%v", entityNamespace, entityName, status, required)
-	return required
+	klog.Infof("Mutation policy for %v/%v: status: %q required:%v reason:%q", namespace, entityName, status, required, reason)
+	return required, reason
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "y", "yes", "true", "on":
+		return true
+	default:
+		return false
+	}
 }
 
 func getServiceAccountTokenVolumeName(cnts []corev1.Container, saTokenPath string) (string, error) {
@@ -110,7 +118,7 @@ Loop:
 	}
 
 	if k8sSaSecretsVolName == "" {
-		err := fmt.Errorf("Volume Mount for path %s not found in submitted pod", saTokenPath)
+		err := ctx.NewInjectionError(ctx.ReasonMissingServiceAccountToken, "Volume Mount for path %s not found in submitted pod", saTokenPath)
 		klog.Error(err.Error())
 		return "", err
 	}
@@ -119,20 +127,37 @@ Loop:
 }
 
 func updateAnnotation(target map[string]string, added map[string]string) (patch []ctx.PatchOperation) {
+	// No annotations at all: add them in one go (we must not add keys to a non-existing map)
+	if len(target) == 0 {
+		if len(added) > 0 {
+			patch = append(patch, ctx.PatchOperation{
+				Op:    ctx.JsonPatchOpAdd,
+				Path:  ctx.JsonPathAnnotations,
+				Value: added,
+			})
+		}
+
+		return patch
+	}
+
 	for key, value := range added {
-		if target == nil || target[key] == "" {
-			target = map[string]string{}
+		// JSON Pointer: escape '~' and '/' in keys (see https://tools.ietf.org/html/rfc6901#section-3)
+		path := ctx.JsonPathAnnotations + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+
+		if current, exists := target[key]; exists {
+			if current == value { // Already set (e.g. by a previous invocation)
+				continue
+			}
+
 			patch = append(patch, ctx.PatchOperation{
-				Op:   ctx.JsonPatchOpAdd,
-				Path: ctx.JsonPathAnnotations,
-				Value: map[string]string{
-					key: value,
-				},
+				Op:    ctx.JsonPatchOpReplace,
+				Path:  path,
+				Value: value,
 			})
 		} else {
 			patch = append(patch, ctx.PatchOperation{
-				Op:    ctx.JsonPatchOpReplace,
-				Path:  ctx.JsonPathAnnotations + "/" + key,
+				Op:    ctx.JsonPatchOpAdd,
+				Path:  path,
 				Value: value,
 			})
 		}
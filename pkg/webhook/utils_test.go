@@ -0,0 +1,67 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMutationRequired(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	annotationsFQ := vaultInjector.Config().VaultInjectorAnnotationsFQ
+	skipList := append(ignoredNamespaces, "monitoring", "team-*")
+
+	injectAnnotation := map[string]string{annotationsFQ[ctx.VaultInjectorAnnotationInjectKey]: "true"}
+
+	tests := []struct {
+		name      string
+		namespace string
+		metadata  metav1.ObjectMeta
+		required  bool
+		reason    string
+	}{
+		{"requested", "default", metav1.ObjectMeta{Annotations: injectAnnotation}, true, ""},
+		{"not requested", "default", metav1.ObjectMeta{}, false, ctx.SkipReasonNotRequested},
+		{"system namespace", "kube-system", metav1.ObjectMeta{Annotations: injectAnnotation}, false, ctx.SkipReasonIgnoredNamespace},
+		{"skipped namespace", "monitoring", metav1.ObjectMeta{Annotations: injectAnnotation}, false, ctx.SkipReasonIgnoredNamespace},
+		{"skipped namespace pattern", "team-a", metav1.ObjectMeta{Annotations: injectAnnotation}, false, ctx.SkipReasonIgnoredNamespace},
+		{"already injected", "default", metav1.ObjectMeta{Annotations: map[string]string{
+			annotationsFQ[ctx.VaultInjectorAnnotationInjectKey]: "true",
+			annotationsFQ[ctx.VaultInjectorAnnotationStatusKey]: ctx.VaultInjectorStatusInjected,
+		}}, true, ""},
+		{"opt-out annotation", "default", metav1.ObjectMeta{Annotations: map[string]string{
+			annotationsFQ[ctx.VaultInjectorAnnotationInjectKey]: "true",
+			annotationsFQ[ctx.VaultInjectorAnnotationSkipKey]:   "true",
+		}}, false, ctx.SkipReasonOptOut},
+		{"opt-out label", "default", metav1.ObjectMeta{
+			Annotations: injectAnnotation,
+			Labels:      map[string]string{annotationsFQ[ctx.VaultInjectorAnnotationSkipKey]: "true"},
+		}, false, ctx.SkipReasonOptOut},
+	}
+
+	for _, test := range tests {
+		required, reason := mutationRequired(skipList, annotationsFQ, test.namespace, &test.metadata)
+		assert.Equal(t, test.required, required, test.name)
+		assert.Equal(t, test.reason, reason, test.name)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"encoding/json"
+	ctx "talend/vault-sidecar-injector/pkg/context"
+	"talend/vault-sidecar-injector/pkg/metrics"
+	"talend/vault-sidecar-injector/pkg/tracing"
+	"time"
+
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// Check that pods requesting injection have been injected (mutating webhook may have been bypassed, e.g. on timeout with
+// 'Ignore' failure policy) and that their annotations are consistent. Called after all mutating webhooks.
+func (vaultInjector *VaultInjector) validate(goctx gocontext.Context, ar *admv1.AdmissionReview) *admv1.AdmissionResponse {
+	var pod corev1.Pod
+	var podNamespace string
+
+	config := vaultInjector.Config()
+
+	defer metrics.ObserveDuration(metrics.HandlerValidate, time.Now())
+
+	req := ar.Request
+
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		klog.Errorf("Could not unmarshal raw object: %v", err)
+		metrics.Rejected(req.Namespace, ctx.ReasonInvalidObject)
+		return &admv1.AdmissionResponse{
+			UID: req.UID,
+			Result: &metav1.Status{
+				Message: err.Error(),
+			},
+		}
+	}
+
+	if pod.Namespace != "" {
+		podNamespace = pod.Namespace
+	} else if req.Namespace != "" {
+		podNamespace = req.Namespace
+	} else {
+		podNamespace = metav1.NamespaceDefault
+	}
+
+	goctx = tracing.WithAttributes(goctx, tracing.RequestAttributes(string(req.UID), podNamespace)...)
+
+	// Injection may have been requested by namespace defaults
+	pod.Annotations = vaultInjector.mergeNamespaceDefaults(config, podNamespace, pod.Annotations)
+
+	if required, _ := mutationRequired(append(ignoredNamespaces, config.SkipNamespaces...), config.VaultInjectorAnnotationsFQ, podNamespace, &pod.ObjectMeta); !required {
+		metrics.Validated(podNamespace)
+		return &admv1.AdmissionResponse{
+			UID:     req.UID,
+			Allowed: true,
+		}
+	}
+
+	var err error
+	if pod.Annotations[config.VaultInjectorAnnotationsFQ[ctx.VaultInjectorAnnotationStatusKey]] != ctx.VaultInjectorStatusInjected {
+		err = ctx.NewInjectionError(ctx.ReasonNotInjected, "Submitted pod requests injection but has not been injected by Vault Sidecar Injector")
+		klog.Error(err.Error())
+	} else {
+		// Re-check annotations and policy: they may have been changed by mutating webhooks called after us
+		var context *ctx.InjectionContext
+		if context, err = vaultInjector.computeContext(goctx, config, pod.Spec.Containers, pod.Labels, pod.Annotations); err == nil {
+			err = checkPolicy(goctx, config, &pod, podNamespace, context)
+		}
+	}
+
+	if err != nil {
+		metrics.Rejected(podNamespace, ctx.GetErrorReason(err))
+		vaultInjector.recordDenial(req, &pod, podNamespace, err)
+		return &admv1.AdmissionResponse{
+			UID:     req.UID,
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: err.Error(),
+			},
+		}
+	}
+
+	metrics.Validated(podNamespace)
+	return &admv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+}
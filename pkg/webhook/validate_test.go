@@ -0,0 +1,114 @@
+// Copyright © 2019-2021 Talend - www.talend.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidate(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	tr := &testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}
+	if _, err = tr.load(); err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	pod := &corev1.Pod{ObjectMeta: tr.podTemplateSpec.ObjectMeta, Spec: tr.podTemplateSpec.Spec}
+	pod.Namespace = tr.namespace
+
+	_, injectedPod, err := vaultInjector.Render(pod.DeepCopy())
+	if err != nil {
+		t.Fatalf("Render error: %s", err)
+	}
+
+	inconsistentPod := injectedPod.DeepCopy()
+	inconsistentPod.Annotations["sidecar.vault.talend.org/secrets-injection-method"] = "env" // not supported with dynamic secrets
+
+	notRequestingPod := pod.DeepCopy()
+	delete(notRequestingPod.Annotations, "sidecar.vault.talend.org/inject")
+
+	tables := []struct {
+		name    string
+		pod     *corev1.Pod
+		allowed bool
+		message string
+	}{
+		{"not requesting injection", notRequestingPod, true, ""},
+		{"not injected", pod, false, "has not been injected"},
+		{"injected", injectedPod, true, ""},
+		{"inconsistent annotations", inconsistentPod, false, "unsupported combination"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			tr.podTemplateSpec = &corev1.PodTemplateSpec{ObjectMeta: table.pod.ObjectMeta, Spec: table.pod.Spec}
+			ar, err := tr.createAdmissionReview()
+			if err != nil {
+				t.Fatalf("Error creating AR: %s", err)
+			}
+
+			resp := vaultInjector.validate(gocontext.Background(), ar)
+			assert.Equal(t, table.allowed, resp.Allowed)
+			assert.Nil(t, resp.Patch)
+			if !table.allowed {
+				assert.Contains(t, resp.Result.Message, table.message)
+			}
+		})
+	}
+}
+
+func TestServeValidate(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	ar, err := (&testResource{manifest: "../../test/workloads/ok/test-app-dep-1.yaml"}).load()
+	if err != nil {
+		t.Fatalf("Error creating AR: %s", err)
+	}
+
+	body, err := json.Marshal(ar)
+	if err != nil {
+		t.Fatalf("Error marshalling AR: %s", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(string(body)))
+	request.Header.Add("Content-Type", "application/json")
+	responseRecorder := httptest.NewRecorder()
+
+	vaultInjector.ServeValidate(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var arOut admv1.AdmissionReview
+	if assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &arOut)) {
+		assert.Equal(t, ar.Request.UID, arOut.Response.UID)
+		assert.False(t, arOut.Response.Allowed)
+	}
+}
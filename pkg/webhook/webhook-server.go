@@ -15,12 +15,17 @@
 package webhook
 
 import (
+	gocontext "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync/atomic"
 	cfg "talend/vault-sidecar-injector/pkg/config"
-	m "talend/vault-sidecar-injector/pkg/mode"
+	"talend/vault-sidecar-injector/pkg/metrics"
+	"talend/vault-sidecar-injector/pkg/tracing"
+	"time"
 
 	admv1 "k8s.io/api/admission/v1"
 	admv1beta1 "k8s.io/api/admission/v1beta1"
@@ -43,12 +48,40 @@ var ignoredNamespaces = []string{
 
 // New : init new VaultInjector type
 func New(config *cfg.VSIConfig, server *http.Server) *VaultInjector {
-	// Add mode annotations
-	for _, mode := range m.VaultInjectorModes {
-		vaultInjectorAnnotationKeys = append(vaultInjectorAnnotationKeys, mode.Annotations...)
+	vaultInjector := &VaultInjector{
+		Server: server,
 	}
 
-	// Compute FQ annotations
+	computeAnnotationsFQ(config)
+	vaultInjector.setConfig(config)
+	return vaultInjector
+}
+
+// Config : return config currently in use
+func (vaultInjector *VaultInjector) Config() *cfg.VSIConfig {
+	return vaultInjector.config.Load().(*cfg.VSIConfig)
+}
+
+// ReloadConfig : atomically replace config in use (pending mutations keep on using previous config). Provided config is discarded if invalid.
+func (vaultInjector *VaultInjector) ReloadConfig(config *cfg.VSIConfig) error {
+	computeAnnotationsFQ(config)
+
+	if err := config.Validate(); err != nil {
+		klog.Errorf("Invalid configuration [sha256sum: %s], keep previous one [sha256sum: %s]: %v", config.Hash, vaultInjector.Config().Hash, err)
+		return err
+	}
+
+	vaultInjector.setConfig(config)
+	klog.Infof("New configuration loaded [sha256sum: %s]", config.Hash)
+	return nil
+}
+
+func (vaultInjector *VaultInjector) setConfig(config *cfg.VSIConfig) {
+	vaultInjector.config.Store(config)
+	metrics.ConfigLoaded(config.Hash)
+}
+
+func computeAnnotationsFQ(config *cfg.VSIConfig) {
 	config.VaultInjectorAnnotationsFQ = make(map[string]string, len(vaultInjectorAnnotationKeys))
 	for _, vaultAnnotationKey := range vaultInjectorAnnotationKeys {
 		if config.VaultInjectorAnnotationKeyPrefix != "" {
@@ -57,23 +90,58 @@ func New(config *cfg.VSIConfig, server *http.Server) *VaultInjector {
 			config.VaultInjectorAnnotationsFQ[vaultAnnotationKey] = vaultAnnotationKey
 		}
 	}
-
-	return &VaultInjector{
-		VSIConfig: config,
-		Server:    server,
-	}
 }
 
-// Serve method for webhook server
+// Serve method for webhook server (mutation)
 func (vaultInjector *VaultInjector) Serve(w http.ResponseWriter, r *http.Request) {
+	defer metrics.ObserveDuration(metrics.HandlerServe, time.Now())
+
+	vaultInjector.serve(w, r, "Serve", vaultInjector.mutate)
+}
+
+// ServeValidate method for webhook server (validation)
+func (vaultInjector *VaultInjector) ServeValidate(w http.ResponseWriter, r *http.Request) {
+	defer metrics.ObserveDuration(metrics.HandlerServeValidate, time.Now())
+
+	vaultInjector.serve(w, r, "ServeValidate", vaultInjector.validate)
+}
+
+// Decode AdmissionReview, process its request with provided function then encode response
+func (vaultInjector *VaultInjector) serve(w http.ResponseWriter, r *http.Request, spanName string, admit admitFunc) {
 	var body []byte
 
+	atomic.AddInt32(&vaultInjector.inFlight, 1)
+	defer atomic.AddInt32(&vaultInjector.inFlight, -1)
+
+	goctx, span := tracing.Start(tracing.FromRequest(r), spanName)
+	defer span.End()
+
 	if klog.V(5) { // enabled by providing '-v=5' at least
 		klog.Infof("HTTP Request=%+v", r)
 	}
 
+	// Reject right away when overloaded: API server will then apply webhook's failure policy
+	if !vaultInjector.acquireRequestSlot() {
+		klog.Error("Too many concurrent admission requests")
+		metrics.RequestRejected(metrics.RejectionTooManyRequests)
+		http.Error(w, "Too many concurrent admission requests", http.StatusTooManyRequests)
+		return
+	}
+	defer vaultInjector.releaseRequestSlot()
+
+	// Honor timeout provided by API server
+	goctx, cancel := requestContext(goctx, r)
+	defer cancel()
+
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
+		data, err := ioutil.ReadAll(vaultInjector.limitBody(w, r.Body))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			klog.Errorf("Request body exceeds %d bytes", maxBytesErr.Limit)
+			metrics.RequestRejected(metrics.RejectionBodyTooLarge)
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		} else if err == nil {
 			body = data
 		}
 	}
@@ -91,7 +159,9 @@ func (vaultInjector *VaultInjector) Serve(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	_, decodeSpan := tracing.Start(goctx, "decode")
 	rawAR, gvk, err := deserializer.Decode(body, nil, nil)
+	tracing.End(decodeSpan, err)
 	if err != nil {
 		klog.Errorf("Can't decode body: %v", err)
 		http.Error(w, fmt.Sprintf("Cannot decode body: %v", err), http.StatusBadRequest)
@@ -131,9 +201,22 @@ func (vaultInjector *VaultInjector) Serve(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	if arIn.Request == nil {
+		klog.Error("AdmissionReview without request")
+		http.Error(w, "Missing request in AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
 	arOut := &admv1.AdmissionReview{}
 	arOut.SetGroupVersionKind(admv1.SchemeGroupVersion.WithKind("AdmissionReview"))
-	arOut.Response = vaultInjector.mutate(arIn)
+	span.SetAttributes(tracing.RequestAttributes(string(arIn.Request.UID), arIn.Request.Namespace)...)
+
+	arOut.Response = admit(goctx, arIn)
+
+	if errors.Is(goctx.Err(), gocontext.DeadlineExceeded) {
+		klog.Errorf("Admission request %v timed out", arIn.Request.UID)
+		metrics.RequestRejected(metrics.RejectionTimeout)
+	}
 
 	var returnedAR interface{}
 	returnedAR = arOut
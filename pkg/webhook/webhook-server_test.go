@@ -142,3 +142,32 @@ func TestWebhookServer(t *testing.T) {
 		})
 	}
 }
+
+func TestReloadConfig(t *testing.T) {
+	vaultInjector, err := createTestVaultInjector()
+	if err != nil {
+		t.Fatalf("Loading error: %s", err)
+	}
+
+	initialConfig := vaultInjector.Config()
+
+	// Invalid config must be discarded
+	invalidConfig := *initialConfig
+	invalidConfig.InjectionConfig = nil
+	invalidConfig.Hash = "invalid"
+	assert.Error(t, vaultInjector.ReloadConfig(&invalidConfig))
+	assert.Equal(t, initialConfig, vaultInjector.Config())
+
+	invalidConfig = *initialConfig
+	invalidConfig.SkipNamespaces = []string{"team-["}
+	invalidConfig.Hash = "invalid"
+	assert.Error(t, vaultInjector.ReloadConfig(&invalidConfig))
+	assert.Equal(t, initialConfig, vaultInjector.Config())
+
+	// Valid config must replace current one
+	newConfig := *initialConfig
+	newConfig.Hash = "new"
+	assert.NoError(t, vaultInjector.ReloadConfig(&newConfig))
+	assert.Equal(t, &newConfig, vaultInjector.Config())
+	assert.NotEmpty(t, vaultInjector.Config().VaultInjectorAnnotationsFQ)
+}
@@ -18,6 +18,12 @@ ${VAULT_POD} "VAULT_TOKEN=root vault secrets enable -version=1 -path=secret kv"
 ${VAULT_POD} "VAULT_TOKEN=root vault secrets enable transit" || true
 ${VAULT_POD} "VAULT_TOKEN=root vault write -f transit/keys/test-key"
 
+# Enable PKI Secrets Engine, generate a root CA and create a test role
+${VAULT_POD} "VAULT_TOKEN=root vault secrets enable pki" || true
+${VAULT_POD} "VAULT_TOKEN=root vault secrets tune -max-lease-ttl=87600h pki"
+${VAULT_POD} "VAULT_TOKEN=root vault write -field=certificate pki/root/generate/internal common_name=\"Test Root CA\" ttl=87600h" > /dev/null
+${VAULT_POD} "VAULT_TOKEN=root vault write pki/roles/test allow_any_name=true max_ttl=72h"
+
 # Enable Vault K8S Auth Method
 echo "-> Enable & set up Vault Kubernetes Auth Method"
 ${VAULT_POD} "VAULT_TOKEN=root vault auth enable kubernetes" || true
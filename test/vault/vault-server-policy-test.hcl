@@ -11,4 +11,9 @@ path "secret/test/test-app-svc/" {
 # Manage the transit secrets engine
 path "transit/*" {
   capabilities = [ "create", "read", "update", "delete", "list" ]
-}
\ No newline at end of file
+}
+
+# Issue certificates from the PKI secrets engine
+path "pki/issue/test" {
+  capabilities = [ "create", "update" ]
+}